@@ -15,6 +15,8 @@ limitations under the License.
 package v1beta1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 )
 
@@ -22,12 +24,32 @@ import (
 
 type OnboardbaseAuth struct {
 	// OnboardbaseAPIKey is the APIKey generated by an admin account.
-	// It is used to recognize and authorize access to a project and environment within onboardbase
-	// +kubebuilder:validation:Required
+	// It is used to recognize and authorize access to a project and environment within onboardbase.
+	// Required unless onboardbaseAPIKeyFile is set.
+	// +optional
 	OnboardbaseAPIKey esmeta.SecretKeySelector `json:"onboardbaseAPIKey"`
-	// OnboardbasePasscode is the passcode attached to the API Key
-	// +kubebuilder:validation:Required
+	// OnboardbasePasscode is the passcode attached to the API Key.
+	// Required unless serverSideDecryption is enabled or
+	// onboardbasePasscodeFile is set.
+	// +optional
 	OnboardbasePasscode esmeta.SecretKeySelector `json:"onboardbasePasscode"`
+	// OnboardbaseAPIKeyFile is the path of a file in the controller's
+	// filesystem holding the API key, e.g. one mounted by the Secrets Store
+	// CSI driver. It takes precedence over onboardbaseAPIKey and is re-read
+	// when it changes.
+	// +optional
+	OnboardbaseAPIKeyFile string `json:"onboardbaseAPIKeyFile,omitempty"`
+	// OnboardbasePasscodeFile is the path of a file holding the passcode. It
+	// takes precedence over onboardbasePasscode and is re-read when it
+	// changes.
+	// +optional
+	OnboardbasePasscodeFile string `json:"onboardbasePasscodeFile,omitempty"`
+	// OnboardbaseFallbackPasscodes are previous passcodes, tried in order on
+	// secrets the passcode does not decrypt while they are re-encrypted
+	// after a rotation. Every secret decrypted with one is counted and
+	// logged.
+	// +optional
+	OnboardbaseFallbackPasscodes []esmeta.SecretKeySelector `json:"onboardbaseFallbackPasscodes,omitempty"`
 }
 
 // OnboardbaseProvider configures a store to sync secrets using the Onboardbase provider.
@@ -36,12 +58,429 @@ type OnboardbaseProvider struct {
 	// Auth configures how the Operator authenticates with the Onboardbase API
 	Auth *OnboardbaseAuth `json:"auth"`
 
+	// APIHost is the base URL of the Onboardbase API.
+	// It may reference environment variables of the controller as ${VAR}.
+	// +kubebuilder:default:="https://public.onboardbase.com/api/v1/"
+	// +optional
+	APIHost string `json:"apiHost,omitempty"`
+	// ReadAPIHost is a read replica of the API that secrets are read from,
+	// while pushes and deletions still go to APIHost. Reads go to APIHost
+	// when unset. May reference environment variables like APIHost.
+	// +optional
+	ReadAPIHost string `json:"readApiHost,omitempty"`
+	// AcceptLanguage is sent as the Accept-Language header of every request,
+	// so that a self-hosted API returns error messages in this language
+	// regardless of its default. ExtraHeaders may override it.
+	// +kubebuilder:default:="en"
+	// +optional
+	AcceptLanguage string `json:"acceptLanguage,omitempty"`
+	// LogVerbosity caps the verbosity of the logs of this store, e.g. 0 to
+	// keep its debug logs out of a busy controller. It can only make the
+	// logs quieter than the controller's log level. Errors are always
+	// logged. Follows the controller's log level when unset.
+	// +kubebuilder:validation:Minimum:=0
+	// +optional
+	LogVerbosity *int `json:"logVerbosity,omitempty"`
+	// ExtraHeaders are HTTP headers sent with every request to the API, e.g. to
+	// route requests through a gateway policy. Headers set on a single remote
+	// key take precedence. The api_key header cannot be overridden.
+	// +optional
+	ExtraHeaders map[string]string `json:"extraHeaders,omitempty"`
+	// KeepaliveInterval makes the client ping the API at this interval while
+	// it is in use, resetting its connections when a ping fails.
+	// Disabled when unset.
+	// +optional
+	KeepaliveInterval *metav1.Duration `json:"keepaliveInterval,omitempty"`
+	// TLSCipherSuites restricts the cipher suites offered to the API to the
+	// named ones, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Only applies to
+	// TLS 1.2 connections; insecure suites are rejected.
+	// +optional
+	TLSCipherSuites []string `json:"tlsCipherSuites,omitempty"`
+	// InsecureSkipVerify disables verification of the API's certificate.
+	// It is refused unless AllowInsecure acknowledges it, so that a typo
+	// cannot disable TLS verification on its own.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+	// AllowInsecure acknowledges InsecureSkipVerify.
+	// +optional
+	AllowInsecure bool `json:"allowInsecure,omitempty"`
+	// FailoverHosts are API base URLs tried in order when a request to APIHost
+	// fails with a network error or a 5xx response.
+	// Writes only fail over when they carry an idempotency key.
+	// +optional
+	FailoverHosts []string `json:"failoverHosts,omitempty"`
+	// Retry retries requests that fail with a network error or a 5xx
+	// response on every host, under the same conditions as failover.
+	// Requests are not retried when unset.
+	// +optional
+	Retry *OnboardbaseRetry `json:"retry,omitempty"`
+	// HedgeReads makes the client send a second, identical read when the
+	// first has not completed after HedgeDelay, using whichever response
+	// succeeds first. Requests are not hedged while rate limited.
+	// +optional
+	HedgeReads bool `json:"hedgeReads,omitempty"`
+	// HedgeDelay is how long a read may take before it is hedged.
+	// Defaults to 200ms.
+	// +optional
+	HedgeDelay *metav1.Duration `json:"hedgeDelay,omitempty"`
+	// ResponseHeaderTimeout limits how long the client waits for the headers
+	// of a response. Requests are only bound by the overall 10s timeout
+	// when unset.
+	// +optional
+	ResponseHeaderTimeout *metav1.Duration `json:"responseHeaderTimeout,omitempty"`
+	// BodyReadTimeout limits how long the client reads the body of a
+	// response once its headers arrived, so that a stalled stream fails
+	// promptly. Requests are only bound by the overall 10s timeout when
+	// unset.
+	// +optional
+	BodyReadTimeout *metav1.Duration `json:"bodyReadTimeout,omitempty"`
+	// SuccessCodes declares, per operation such as "POST /secrets", the
+	// status codes a successful response may have. Any other status fails
+	// the request, even a 2xx, to catch requests misrouted by a proxy.
+	// Operations not listed accept any 2xx or 3xx status.
+	// +optional
+	SuccessCodes map[string][]int `json:"successCodes,omitempty"`
+
+	// CircuitBreaker stops sending requests of an operation type while the
+	// API keeps failing them, with independent circuits for reads and
+	// writes. Requests are always sent when unset.
+	// +optional
+	CircuitBreaker *OnboardbaseCircuitBreaker `json:"circuitBreaker,omitempty"`
+
+	// PayloadHosts are the hosts, besides the API host, that the API may
+	// point to with a pre-signed URL serving secrets instead of returning
+	// them inline, e.g. "storage.example.com". Pre-signed URLs are fetched
+	// without credentials.
+	// +optional
+	PayloadHosts []string `json:"payloadHosts,omitempty"`
+
+	// RequestSigning signs every request to the API with an HMAC, for
+	// gateways that authenticate requests that way.
+	// +optional
+	RequestSigning *OnboardbaseRequestSigning `json:"requestSigning,omitempty"`
+
+	// GraphQL fetches secrets with a GraphQL query instead of the REST
+	// /secrets endpoint, for self-hosted deployments exposing one. Pushes
+	// and other requests still use the REST API.
+	// +optional
+	GraphQL *OnboardbaseGraphQL `json:"graphQL,omitempty"`
+
 	// Project is an onboardbase project that the secrets should be pulled from
 	// +kubebuilder:validation:Required
 	// +kubebuilder:default:="development"
 	Project string `json:"onboardbaseProject"`
+	// TokenScopedProject leaves Project out of API requests, for API keys
+	// scoped to a single project that the API applies on its own and that
+	// may be rejected when a project is passed. Project is still used to
+	// identify the store's secrets locally. Remote keys and the "projects"
+	// tag of dataFrom.find cannot override the project then.
+	// +optional
+	TokenScopedProject bool `json:"tokenScopedProject,omitempty"`
 	// Environment is the name of an environmnent within a project to pull the secrets from
 	// +kubebuilder:validation:Required
 	// +kubebuilder:default:="development"
 	Environment string `json:"onboardbaseEnvironment"`
+	// EnvironmentFromNamespace reads the environment from a label or an
+	// annotation of the namespace of the ExternalSecret or PushSecret, for
+	// clusters where namespaces map to environments. It takes precedence
+	// over Environment, which is still used without a namespace.
+	// +optional
+	EnvironmentFromNamespace *OnboardbaseNamespaceKey `json:"environmentFromNamespace,omitempty"`
+	// ParentEnvironment is an environment of the same project that the
+	// environment inherits from. Fetching the whole environment, as
+	// dataFrom.find does, also fetches the parent and merges both, with the
+	// environment's own secrets overriding the parent's.
+	// +optional
+	ParentEnvironment string `json:"parentEnvironment,omitempty"`
+
+	// AsyncAuthProbe checks the credentials in the background with a short
+	// timeout when a client is created, instead of leaving it to the first
+	// request. A failed probe is logged and reported by store validation.
+	// +optional
+	AsyncAuthProbe bool `json:"asyncAuthProbe,omitempty"`
+
+	// ValidationProbes is how often store validation authenticates against
+	// the API. The store is reported invalid when most probes fail, and its
+	// status unknown when only some do.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=3
+	// +optional
+	ValidationProbes int `json:"validationProbes,omitempty"`
+
+	// ValidateScope makes the client check that Project and Environment exist
+	// when it is created, at the cost of an extra API call.
+	// +optional
+	ValidateScope bool `json:"validateScope,omitempty"`
+
+	// AllowedKeys restricts the secrets the store exposes to keys matching
+	// one of these glob patterns, e.g. "APP_*". All keys when empty.
+	// Patterns use Go path.Match syntax, where "*" does not match "/".
+	// +optional
+	AllowedKeys []string `json:"allowedKeys,omitempty"`
+
+	// DeniedKeys are glob patterns of keys the store never exposes, even when
+	// allowed by AllowedKeys. Denied keys are reported as not found.
+	// +optional
+	DeniedKeys []string `json:"deniedKeys,omitempty"`
+
+	// KeyPrefix namespaces the secrets of the store, e.g. "k8s/myapp/", so
+	// that they do not collide with secrets managed by hand. It is prepended
+	// to every key pushed, read or deleted by name. Fetching a whole
+	// environment returns only the keys starting with it, with the prefix
+	// stripped. AllowedKeys and DeniedKeys match keys without the prefix.
+	// +optional
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+
+	// TrimSpace removes leading and trailing whitespace from secret values after they are decrypted.
+	// Disabled by default, as some secrets legitimately contain significant whitespace.
+	// +optional
+	TrimSpace bool `json:"trimSpace,omitempty"`
+
+	// ReferencePrefix enables references between secrets: a value starting
+	// with this prefix, e.g. "ref:", is replaced by the value of the secret
+	// named after it in the same environment. Disabled when empty.
+	// +optional
+	ReferencePrefix string `json:"referencePrefix,omitempty"`
+
+	// AllowEmptyValues returns secrets whose value is empty as such. By
+	// default they are treated as missing, like keys that do not exist.
+	// +optional
+	AllowEmptyValues bool `json:"allowEmptyValues,omitempty"`
+
+	// RawPayloads is a debugging escape hatch for secrets that are not
+	// key/value objects once decrypted. The decrypted payloads are returned
+	// unparsed, as a JSON array, for the "*" key; no other key resolves.
+	// Payloads cannot be filtered by key, so it cannot be combined with
+	// AllowedKeys, DeniedKeys or KeyPrefix.
+	// +optional
+	RawPayloads bool `json:"rawPayloads,omitempty"`
+
+	// DuplicateKeyPolicy decides which value is kept when Onboardbase returns
+	// the same key more than once: the last one, the first one, or none,
+	// failing the fetch. Duplicates are logged with either of the former.
+	// +kubebuilder:validation:Enum=LastWins;FirstWins;Error
+	// +kubebuilder:default="LastWins"
+	// +optional
+	DuplicateKeyPolicy OnboardbaseDuplicateKeyPolicy `json:"duplicateKeyPolicy,omitempty"`
+
+	// ServerSideDecryption is for Onboardbase setups that decrypt secrets on the
+	// server and return them as plaintext. No passcode is needed in this mode.
+	// +optional
+	ServerSideDecryption bool `json:"serverSideDecryption,omitempty"`
+
+	// StrictDecode rejects API responses carrying fields this provider does not
+	// know, to detect changes of the Onboardbase API schema early.
+	// +optional
+	StrictDecode bool `json:"strictDecode,omitempty"`
+
+	// PushMergeStrategy controls how PushSecret handles a JSON value that
+	// already exists remotely. Replace overwrites it; MergeLocalWins and
+	// MergeRemoteWins deep-merge the pushed fields into it, resolving
+	// conflicting fields in favor of the pushed or the remote value.
+	// +kubebuilder:validation:Enum=Replace;MergeLocalWins;MergeRemoteWins
+	// +kubebuilder:default="Replace"
+	// +optional
+	PushMergeStrategy OnboardbasePushMergeStrategy `json:"pushMergeStrategy,omitempty"`
+
+	// MaxValueSize is the largest secret value in bytes that PushSecret sends
+	// to Onboardbase. Larger values are rejected before calling the API.
+	// +kubebuilder:default:=65536
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	MaxValueSize int `json:"maxValueSize,omitempty"`
+
+	// EncryptPushedSecrets makes PushSecret encrypt secrets with the passcode
+	// before sending them, in the format secrets are read in, so that they
+	// are not sent as plaintext. It requires a passcode and cannot be
+	// combined with serverSideDecryption.
+	// +optional
+	EncryptPushedSecrets bool `json:"encryptPushedSecrets,omitempty"`
+
+	// CompressPushes makes PushSecret gzip request bodies of at least
+	// CompressionThreshold bytes, sent with Content-Encoding: gzip. If the
+	// API rejects the encoding, bodies are sent uncompressed from then on.
+	// +optional
+	CompressPushes bool `json:"compressPushes,omitempty"`
+
+	// CompressionThreshold is the size in bytes from which pushed request
+	// bodies are compressed. Defaults to 8192.
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	CompressionThreshold int `json:"compressionThreshold,omitempty"`
+
+	// ReadAfterWriteTimeout makes PushSecret wait, after each write, until
+	// Onboardbase returns the pushed value, polling with a backoff for at most
+	// this long. A GetSecret that follows the push then never reads the
+	// previous value. The push fails when the value is not readable in time.
+	// +optional
+	ReadAfterWriteTimeout *metav1.Duration `json:"readAfterWriteTimeout,omitempty"`
+
+	// MaxRefTimeout is the longest deadline an ExternalSecret may set for a
+	// fetch, with the timeout option of a remote key, e.g.
+	// "REPORT?timeout=90s", or the "timeout" tag of dataFrom.find. Longer
+	// timeouts are rejected. Defaults to 2m.
+	// +optional
+	MaxRefTimeout *metav1.Duration `json:"maxRefTimeout,omitempty"`
+
+	// PushKeyPrefix is prepended to the keys pushed for a PushSecret entry
+	// that names no secretKey, which pushes every key of the Secret. It
+	// comes after KeyPrefix.
+	// +optional
+	PushKeyPrefix string `json:"pushKeyPrefix,omitempty"`
+
+	// SecretFields maps the fields of a decrypted secret object to its key and value.
+	// Only needed when the Onboardbase API returns secrets with non-default field names.
+	// +optional
+	SecretFields *OnboardbaseSecretFields `json:"secretFields,omitempty"`
+
+	// Cache keeps the last fetched value of each secret in a Kubernetes
+	// Secret. GetSecret serves it while the API is unreachable. Cached
+	// values are stored in plaintext in that Secret, so whoever can read it
+	// can read every cached secret.
+	// +optional
+	Cache *OnboardbaseCache `json:"cache,omitempty"`
+}
+
+type OnboardbaseDuplicateKeyPolicy string
+
+const (
+	OnboardbaseDuplicateKeysLastWins  OnboardbaseDuplicateKeyPolicy = "LastWins"
+	OnboardbaseDuplicateKeysFirstWins OnboardbaseDuplicateKeyPolicy = "FirstWins"
+	OnboardbaseDuplicateKeysError     OnboardbaseDuplicateKeyPolicy = "Error"
+)
+
+type OnboardbasePushMergeStrategy string
+
+const (
+	OnboardbasePushReplace         OnboardbasePushMergeStrategy = "Replace"
+	OnboardbasePushMergeLocalWins  OnboardbasePushMergeStrategy = "MergeLocalWins"
+	OnboardbasePushMergeRemoteWins OnboardbasePushMergeStrategy = "MergeRemoteWins"
+)
+
+type OnboardbaseBackoffStrategy string
+
+const (
+	OnboardbaseBackoffConstant    OnboardbaseBackoffStrategy = "Constant"
+	OnboardbaseBackoffLinear      OnboardbaseBackoffStrategy = "Linear"
+	OnboardbaseBackoffExponential OnboardbaseBackoffStrategy = "Exponential"
+)
+
+// OnboardbaseRetry configures how failed requests are retried.
+type OnboardbaseRetry struct {
+	// MaxRetries is how often a failed request is retried.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=3
+	// +optional
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// Backoff is how the wait grows between retries: the same BaseInterval
+	// every time, BaseInterval times the retry number, or doubling from
+	// BaseInterval with jitter. Waits never exceed MaxInterval.
+	// +kubebuilder:validation:Enum=Constant;Linear;Exponential
+	// +kubebuilder:default="Exponential"
+	// +optional
+	Backoff OnboardbaseBackoffStrategy `json:"backoff,omitempty"`
+	// BaseInterval is the wait before the first retry. Defaults to 200ms.
+	// +optional
+	BaseInterval *metav1.Duration `json:"baseInterval,omitempty"`
+	// MaxInterval caps the wait between retries. Defaults to 5s.
+	// +optional
+	MaxInterval *metav1.Duration `json:"maxInterval,omitempty"`
+	// RetryableStatusCodes are response status codes retried in addition to
+	// 429 and every 5xx code, e.g. 408 and 425 for backends that use them for
+	// transient conditions. Network errors are always retried.
+	// +optional
+	RetryableStatusCodes []int `json:"retryableStatusCodes,omitempty"`
+}
+
+// OnboardbaseNamespaceKey names a label or an annotation of a namespace.
+// Exactly one must be set.
+type OnboardbaseNamespaceKey struct {
+	// Label is the key of the namespace label.
+	// +optional
+	Label string `json:"label,omitempty"`
+	// Annotation is the key of the namespace annotation.
+	// +optional
+	Annotation string `json:"annotation,omitempty"`
+}
+
+// OnboardbaseCircuitBreaker configures a circuit per operation type, so
+// that failing pushes do not stop secrets from being read.
+type OnboardbaseCircuitBreaker struct {
+	// Reads configures the circuit of requests reading secrets.
+	// +optional
+	Reads *OnboardbaseCircuit `json:"reads,omitempty"`
+	// Writes configures the circuit of requests pushing or deleting secrets.
+	// +optional
+	Writes *OnboardbaseCircuit `json:"writes,omitempty"`
+}
+
+// OnboardbaseCircuit configures the circuit breaker of an operation type.
+type OnboardbaseCircuit struct {
+	// FailureThreshold is how many consecutive requests may fail because the
+	// API is unavailable before the circuit opens.
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold int `json:"failureThreshold"`
+	// Cooldown is how long an open circuit fails requests before letting one
+	// through to probe the API. Defaults to 30s.
+	// +optional
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
+}
+
+// OnboardbaseSecretFields names the fields holding a secret's key and value.
+type OnboardbaseSecretFields struct {
+	// Key is the name of the field holding the secret key.
+	// +kubebuilder:default:="key"
+	// +optional
+	Key string `json:"key,omitempty"`
+	// Value is the name of the field holding the secret value.
+	// +kubebuilder:default:="value"
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// OnboardbaseCache configures the fallback cache of fetched secret values.
+type OnboardbaseCache struct {
+	// SecretName is the name of the Secret holding the cache. The controller
+	// creates and updates it in the namespace of the ExternalSecret.
+	// +kubebuilder:validation:Required
+	SecretName string `json:"secretName"`
+	// MaxStaleness is how old a cached value may be and still be served.
+	// Older values are refused. Defaults to 1h.
+	// +optional
+	MaxStaleness *metav1.Duration `json:"maxStaleness,omitempty"`
+}
+
+// OnboardbaseGraphQL configures fetching secrets from a GraphQL endpoint.
+type OnboardbaseGraphQL struct {
+	// Path is the path of the GraphQL endpoint on the API host.
+	// +kubebuilder:default:="/graphql"
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Query replaces the default query. It receives the project,
+	// environment and modifiedSince variables, and its result must be a
+	// field named secrets shaped like the data object of the REST
+	// /secrets response.
+	// +optional
+	Query string `json:"query,omitempty"`
+}
+
+// OnboardbaseRequestSigning configures HMAC signing of API requests. The
+// signature is the hex-encoded HMAC-SHA256 of the request method, URL path,
+// encoded query, Unix timestamp and body, joined by newlines. The timestamp
+// is sent in the X-Signature-Timestamp header.
+type OnboardbaseRequestSigning struct {
+	// KeySecretRef references the shared signing key.
+	// +kubebuilder:validation:Required
+	KeySecretRef esmeta.SecretKeySelector `json:"keySecretRef"`
+	// Header is the HTTP header carrying the signature.
+	// +kubebuilder:default:="X-Signature"
+	// +optional
+	Header string `json:"header,omitempty"`
+	// MaxBodySize is the largest request body in bytes that is signed.
+	// Signing needs the whole body in memory, so requests with a larger
+	// body, such as a PushSecret of a very large value, fail before being
+	// sent. Defaults to 1048576 (1 MiB).
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	MaxBodySize int `json:"maxBodySize,omitempty"`
 }
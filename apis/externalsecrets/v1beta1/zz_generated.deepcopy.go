@@ -1409,6 +1409,13 @@ func (in *OnboardbaseAuth) DeepCopyInto(out *OnboardbaseAuth) {
 	*out = *in
 	in.OnboardbaseAPIKey.DeepCopyInto(&out.OnboardbaseAPIKey)
 	in.OnboardbasePasscode.DeepCopyInto(&out.OnboardbasePasscode)
+	if in.OnboardbaseFallbackPasscodes != nil {
+		in, out := &in.OnboardbaseFallbackPasscodes, &out.OnboardbaseFallbackPasscodes
+		*out = make([]metav1.SecretKeySelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseAuth.
@@ -1421,6 +1428,101 @@ func (in *OnboardbaseAuth) DeepCopy() *OnboardbaseAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseCache) DeepCopyInto(out *OnboardbaseCache) {
+	*out = *in
+	if in.MaxStaleness != nil {
+		in, out := &in.MaxStaleness, &out.MaxStaleness
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseCache.
+func (in *OnboardbaseCache) DeepCopy() *OnboardbaseCache {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseCircuit) DeepCopyInto(out *OnboardbaseCircuit) {
+	*out = *in
+	if in.Cooldown != nil {
+		in, out := &in.Cooldown, &out.Cooldown
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseCircuit.
+func (in *OnboardbaseCircuit) DeepCopy() *OnboardbaseCircuit {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseCircuit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseCircuitBreaker) DeepCopyInto(out *OnboardbaseCircuitBreaker) {
+	*out = *in
+	if in.Reads != nil {
+		in, out := &in.Reads, &out.Reads
+		*out = new(OnboardbaseCircuit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Writes != nil {
+		in, out := &in.Writes, &out.Writes
+		*out = new(OnboardbaseCircuit)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseCircuitBreaker.
+func (in *OnboardbaseCircuitBreaker) DeepCopy() *OnboardbaseCircuitBreaker {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseCircuitBreaker)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseGraphQL) DeepCopyInto(out *OnboardbaseGraphQL) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseGraphQL.
+func (in *OnboardbaseGraphQL) DeepCopy() *OnboardbaseGraphQL {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseGraphQL)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseNamespaceKey) DeepCopyInto(out *OnboardbaseNamespaceKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseNamespaceKey.
+func (in *OnboardbaseNamespaceKey) DeepCopy() *OnboardbaseNamespaceKey {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseNamespaceKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OnboardbaseProvider) DeepCopyInto(out *OnboardbaseProvider) {
 	*out = *in
@@ -1429,6 +1531,124 @@ func (in *OnboardbaseProvider) DeepCopyInto(out *OnboardbaseProvider) {
 		*out = new(OnboardbaseAuth)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.LogVerbosity != nil {
+		in, out := &in.LogVerbosity, &out.LogVerbosity
+		*out = new(int)
+		**out = **in
+	}
+	if in.ExtraHeaders != nil {
+		in, out := &in.ExtraHeaders, &out.ExtraHeaders
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KeepaliveInterval != nil {
+		in, out := &in.KeepaliveInterval, &out.KeepaliveInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TLSCipherSuites != nil {
+		in, out := &in.TLSCipherSuites, &out.TLSCipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailoverHosts != nil {
+		in, out := &in.FailoverHosts, &out.FailoverHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(OnboardbaseRetry)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HedgeDelay != nil {
+		in, out := &in.HedgeDelay, &out.HedgeDelay
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ResponseHeaderTimeout != nil {
+		in, out := &in.ResponseHeaderTimeout, &out.ResponseHeaderTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.BodyReadTimeout != nil {
+		in, out := &in.BodyReadTimeout, &out.BodyReadTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.SuccessCodes != nil {
+		in, out := &in.SuccessCodes, &out.SuccessCodes
+		*out = make(map[string][]int, len(*in))
+		for key, val := range *in {
+			var outVal []int
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]int, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.CircuitBreaker != nil {
+		in, out := &in.CircuitBreaker, &out.CircuitBreaker
+		*out = new(OnboardbaseCircuitBreaker)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PayloadHosts != nil {
+		in, out := &in.PayloadHosts, &out.PayloadHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequestSigning != nil {
+		in, out := &in.RequestSigning, &out.RequestSigning
+		*out = new(OnboardbaseRequestSigning)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GraphQL != nil {
+		in, out := &in.GraphQL, &out.GraphQL
+		*out = new(OnboardbaseGraphQL)
+		**out = **in
+	}
+	if in.EnvironmentFromNamespace != nil {
+		in, out := &in.EnvironmentFromNamespace, &out.EnvironmentFromNamespace
+		*out = new(OnboardbaseNamespaceKey)
+		**out = **in
+	}
+	if in.AllowedKeys != nil {
+		in, out := &in.AllowedKeys, &out.AllowedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeniedKeys != nil {
+		in, out := &in.DeniedKeys, &out.DeniedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReadAfterWriteTimeout != nil {
+		in, out := &in.ReadAfterWriteTimeout, &out.ReadAfterWriteTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxRefTimeout != nil {
+		in, out := &in.MaxRefTimeout, &out.MaxRefTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.SecretFields != nil {
+		in, out := &in.SecretFields, &out.SecretFields
+		*out = new(OnboardbaseSecretFields)
+		**out = **in
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(OnboardbaseCache)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseProvider.
@@ -1441,6 +1661,67 @@ func (in *OnboardbaseProvider) DeepCopy() *OnboardbaseProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseRequestSigning) DeepCopyInto(out *OnboardbaseRequestSigning) {
+	*out = *in
+	in.KeySecretRef.DeepCopyInto(&out.KeySecretRef)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseRequestSigning.
+func (in *OnboardbaseRequestSigning) DeepCopy() *OnboardbaseRequestSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseRequestSigning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseRetry) DeepCopyInto(out *OnboardbaseRetry) {
+	*out = *in
+	if in.BaseInterval != nil {
+		in, out := &in.BaseInterval, &out.BaseInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxInterval != nil {
+		in, out := &in.MaxInterval, &out.MaxInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RetryableStatusCodes != nil {
+		in, out := &in.RetryableStatusCodes, &out.RetryableStatusCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseRetry.
+func (in *OnboardbaseRetry) DeepCopy() *OnboardbaseRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OnboardbaseSecretFields) DeepCopyInto(out *OnboardbaseSecretFields) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OnboardbaseSecretFields.
+func (in *OnboardbaseSecretFields) DeepCopy() *OnboardbaseSecretFields {
+	if in == nil {
+		return nil
+	}
+	out := new(OnboardbaseSecretFields)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OnePasswordAuth) DeepCopyInto(out *OnePasswordAuth) {
 	*out = *in
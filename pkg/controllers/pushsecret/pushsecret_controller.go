@@ -49,6 +49,14 @@ const (
 	pushSecretFinalizer       = "pushsecret.externalsecrets.io/finalizer"
 )
 
+// WholeSecretPusher is implemented by secrets clients that can push every key
+// of a Secret at once, for PushSecret data that names no secretKey. The
+// provider names the remote secrets itself, so it returns the remote key each
+// key of data was written to, for them to be tracked and deleted one by one.
+type WholeSecretPusher interface {
+	PushAllSecrets(ctx context.Context, data map[string][]byte, remoteRef v1beta1.PushRemoteRef) (map[string]string, error)
+}
+
 type Reconciler struct {
 	client.Client
 	Log             logr.Logger
@@ -246,6 +254,27 @@ func (r *Reconciler) PushSecretToProviders(ctx context.Context, stores map[esapi
 			return out, fmt.Errorf("could not get secrets client for store %v: %w", store.GetName(), err)
 		}
 		for _, ref := range ps.Spec.Data {
+			if ref.Match.SecretKey == "" {
+				pusher, ok := client.(WholeSecretPusher)
+				if !ok {
+					return out, fmt.Errorf("store %v does not support pushing a whole secret, set a secret key", store.GetName())
+				}
+				pushed, err := pusher.PushAllSecrets(ctx, secret.Data, ref.Match.RemoteRef)
+				if err != nil {
+					return out, fmt.Errorf(errSetSecretFailed, secret.GetName(), store.GetName(), err)
+				}
+				// track each pushed key on its own, so that it is deleted by
+				// its remote key once it leaves the Secret.
+				for secretKey, remoteKey := range pushed {
+					out[storeKey][remoteKey] = esapi.PushSecretData{
+						Match: esapi.PushSecretMatch{
+							SecretKey: secretKey,
+							RemoteRef: esapi.PushSecretRemoteRef{RemoteKey: remoteKey},
+						},
+					}
+				}
+				continue
+			}
 			secretValue, ok := secret.Data[ref.Match.SecretKey]
 			if !ok {
 				return out, fmt.Errorf("secret key %v does not exist", ref.Match.SecretKey)
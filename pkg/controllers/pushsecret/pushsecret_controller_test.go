@@ -41,6 +41,8 @@ var (
 	interval     = time.Millisecond * 250
 )
 
+var _ WholeSecretPusher = &fake.Client{}
+
 type testCase struct {
 	store      v1beta1.GenericStore
 	pushsecret *v1alpha1.PushSecret
@@ -616,6 +618,58 @@ var _ = Describe("ExternalSecret controller", func() {
 			return checkCondition(ps.Status, expected)
 		}
 	}
+	// a whole secret is tracked, and deleted, key by key.
+	syncWholeSecret := func(tc *testCase) {
+		fakeProvider.SetSecretFn = func() error {
+			return nil
+		}
+		fakeProvider.DeleteSecretFn = func() error {
+			return nil
+		}
+		tc.pushsecret.Spec.DeletionPolicy = v1alpha1.PushSecretDeletionPolicyDelete
+		tc.pushsecret.Spec.Data[0].Match = v1alpha1.PushSecretMatch{}
+		tc.secret.Data["other"] = []byte("other-value")
+		tc.assert = func(ps *v1alpha1.PushSecret, secret *v1.Secret) bool {
+			storeKey := fmt.Sprintf("SecretStore/%v", PushSecretStore)
+			psKey := types.NamespacedName{Name: PushSecretName, Namespace: PushSecretNamespace}
+			updatedPS := &v1alpha1.PushSecret{}
+			Eventually(func() bool {
+				By("checking if each key of the Secret is tracked")
+				err := k8sClient.Get(context.Background(), psKey, updatedPS)
+				if err != nil {
+					return false
+				}
+				synced := updatedPS.Status.SyncedPushSecrets[storeKey]
+				if len(synced) != 2 {
+					return false
+				}
+				for _, key := range []string{"key", "other"} {
+					data, ok := synced[key]
+					if !ok || data.Match.SecretKey != key || data.Match.RemoteRef.RemoteKey != key {
+						return false
+					}
+					if _, ok := fakeProvider.SetSecretArgs[key]; !ok {
+						return false
+					}
+				}
+				return true
+			}, time.Second*10, time.Second).Should(BeTrue())
+
+			delete(secret.Data, "other")
+			Expect(k8sClient.Update(context.Background(), secret, &client.UpdateOptions{})).Should(Succeed())
+			Eventually(func() bool {
+				By("checking if a key removed from the Secret is no longer tracked")
+				err := k8sClient.Get(context.Background(), psKey, updatedPS)
+				if err != nil {
+					return false
+				}
+				synced := updatedPS.Status.SyncedPushSecrets[storeKey]
+				_, ok := synced["key"]
+				return ok && len(synced) == 1
+			}, time.Second*10, time.Second).Should(BeTrue())
+			return true
+		}
+	}
 	// if target Secret name is not specified it should use the ExternalSecret name.
 	failNoSecretStore := func(tc *testCase) {
 		fakeProvider.SetSecretFn = func() error {
@@ -718,6 +772,7 @@ var _ = Describe("ExternalSecret controller", func() {
 		Entry("should sync with ClusterStore", syncWithClusterStore),
 		Entry("should sync with ClusterStore matching labels", syncWithClusterStoreMatchingLabels),
 		Entry("should fail if Secret is not created", failNoSecret),
+		Entry("should sync and track every key of a whole Secret", syncWholeSecret),
 		Entry("should fail if Secret Key does not exist", failNoSecretKey),
 		Entry("should fail if SetSecret fails", setSecretFail),
 		Entry("should fail if no valid SecretStore", failNoSecretStore),
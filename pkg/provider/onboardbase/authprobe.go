@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// asyncAuthProbeTimeout bounds the authentication probe started by NewClient.
+const asyncAuthProbeTimeout = 5 * time.Second
+
+const errAuthProbe = "authentication probe failed: %w"
+
+var log = ctrl.Log.WithName("provider").WithName("onboardbase")
+
+// authProbe checks the API credentials in the background, so that creating a
+// client is not gated on a slow authentication endpoint.
+type authProbe struct {
+	done chan struct{}
+	err  error
+}
+
+func startAuthProbe(onboardbase SecretsClientInterface, timeout time.Duration, logger logr.Logger) *authProbe {
+	probe := &authProbe{done: make(chan struct{})}
+	go func() {
+		defer close(probe.done)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := onboardbase.Authenticate(ctx); err != nil {
+			logger.Error(err, "asynchronous authentication probe failed")
+			probe.err = fmt.Errorf(errAuthProbe, err)
+		}
+	}()
+	return probe
+}
+
+// logger returns the logger of the store, or the provider's logger for
+// clients not built by NewClient.
+func (c *Client) logger() logr.Logger {
+	if c.log.GetSink() == nil {
+		return log
+	}
+	return c.log
+}
+
+// result returns the error of a finished probe. It does not wait for a probe
+// that is still running, and returns nil in that case.
+func (p *authProbe) result() error {
+	select {
+	case <-p.done:
+		return p.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultCacheMaxStaleness is used when the cache sets no maxStaleness.
+	defaultCacheMaxStaleness = time.Hour
+
+	// cacheDataKey is the key of the cache Secret holding the entries.
+	cacheDataKey = "cache.json"
+
+	cacheManagedByLabel = "app.kubernetes.io/managed-by"
+	cacheManagedByValue = "external-secrets-onboardbase"
+
+	errCacheMiss  = "no cached value for %s"
+	errCacheStale = "cached value for %s is stale: fetched %s ago, at most %s allowed"
+)
+
+// cacheEntry is a cached secret value and when it was fetched.
+type cacheEntry struct {
+	Value     []byte    `json:"value"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// secretCache persists fetched secret values in a Kubernetes Secret so that
+// they can be served while the Onboardbase API is unreachable. Entries are
+// keyed by project, environment and secret name, so stores may share it.
+// Values are copied in plaintext into the Secret's namespace, so anyone who
+// can read that Secret can read every cached value.
+type secretCache struct {
+	kube         kclient.Client
+	key          types.NamespacedName
+	maxStaleness time.Duration
+	now          func() time.Time
+}
+
+func newSecretCache(kube kclient.Client, namespace, name string, maxStaleness time.Duration) *secretCache {
+	if maxStaleness <= 0 {
+		maxStaleness = defaultCacheMaxStaleness
+	}
+	return &secretCache{
+		kube:         kube,
+		key:          types.NamespacedName{Namespace: namespace, Name: name},
+		maxStaleness: maxStaleness,
+		now:          time.Now,
+	}
+}
+
+func cacheEntryKey(project, environment, name string) string {
+	return project + "/" + environment + "/" + name
+}
+
+// get returns the cached value of key, unless it is missing or older than
+// maxStaleness.
+func (c *secretCache) get(ctx context.Context, key string) ([]byte, error) {
+	secret := &corev1.Secret{}
+	if err := c.kube.Get(ctx, c.key, secret); err != nil {
+		return nil, err
+	}
+	entries, err := readCacheEntries(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := entries[key]
+	if !ok {
+		return nil, fmt.Errorf(errCacheMiss, key)
+	}
+	if age := c.now().Sub(entry.FetchedAt); age > c.maxStaleness {
+		return nil, fmt.Errorf(errCacheStale, key, age.Round(time.Second), c.maxStaleness)
+	}
+	return entry.Value, nil
+}
+
+// put stores value as the current value of key, creating the Secret if
+// needed. An unchanged value is only written again once it is halfway to
+// maxStaleness, to refresh its fetch time, so that steady reads do not
+// update the Secret every time. Writes racing with other controllers are
+// retried.
+func (c *secretCache) put(ctx context.Context, key string, value []byte) error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		return c.tryPut(ctx, key, value)
+	})
+}
+
+func (c *secretCache) tryPut(ctx context.Context, key string, value []byte) error {
+	secret := &corev1.Secret{}
+	err := c.kube.Get(ctx, c.key, secret)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.key.Name,
+				Namespace: c.key.Namespace,
+				Labels:    map[string]string{cacheManagedByLabel: cacheManagedByValue},
+			},
+		}
+		if err := writeCacheEntries(secret, map[string]cacheEntry{key: {Value: value, FetchedAt: c.now()}}); err != nil {
+			return err
+		}
+		return c.kube.Create(ctx, secret)
+	}
+	if err != nil {
+		return err
+	}
+
+	entries, err := readCacheEntries(secret)
+	if err != nil {
+		return err
+	}
+	if entry, ok := entries[key]; ok && bytes.Equal(entry.Value, value) && c.now().Sub(entry.FetchedAt) < c.maxStaleness/2 {
+		return nil
+	}
+	entries[key] = cacheEntry{Value: value, FetchedAt: c.now()}
+	if err := writeCacheEntries(secret, entries); err != nil {
+		return err
+	}
+	return c.kube.Update(ctx, secret)
+}
+
+func readCacheEntries(secret *corev1.Secret) (map[string]cacheEntry, error) {
+	entries := map[string]cacheEntry{}
+	data, ok := secret.Data[cacheDataKey]
+	if !ok {
+		return entries, nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unable to read cache secret %s: %w", secret.Name, err)
+	}
+	return entries, nil
+}
+
+func writeCacheEntries(secret *corev1.Secret, entries map[string]cacheEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[cacheDataKey] = data
+	return nil
+}
@@ -15,39 +15,88 @@ limitations under the License.
 package onboardbase
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
+	esmeta "github.com/external-secrets/external-secrets/apis/meta/v1"
 	"github.com/external-secrets/external-secrets/pkg/find"
 	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
 	"github.com/external-secrets/external-secrets/pkg/utils"
 )
 
 const (
-	errGetSecret                                       = "could not get secret %s: %s"
-	errGetSecrets                                      = "could not get secrets %s"
-	errUnmarshalSecretMap                              = "unable to unmarshal secret %s: %w"
+	errGetSecret                                            = "could not get secret %s: %s"
+	errGetSecrets                                           = "could not get secrets %s"
+	errPushSecret                                           = "could not push secret %s: %w"
+	errRotateSecret                                         = "could not generate a new value for secret %s: %w"
+	errValueTooLarge                                        = "value of %d bytes exceeds %d bytes"
+	errDeleteSecret                                         = "could not delete secret %s: %w"
+	errDeleteSecrets                                        = "could not delete secrets: %w"
+	errMarshalSecrets                                       = "unable to marshal secrets: %w"
+	errUnmarshalSecretMap                                   = "unable to unmarshal secret %s: %w"
 	errOnboardbaseAPIKeySecretName                          = "missing auth.secretRef.onboardbaseAPIKey.name"
 	errInvalidClusterStoreMissingOnboardbaseAPIKeyNamespace = "missing auth.secretRef.onboardbaseAPIKey.namespace"
 	errFetchOnboardbaseAPIKeySecret                         = "unable to find find OnboardbaseAPIKey secret: %w"
 	errMissingOnboardbaseAPIKey                             = "auth.secretRef.onboardbaseAPIKey.key '%s' not found in secret '%s'"
+	errValidationProbes                                     = "%d of %d validation probes failed: %w"
+	errMissingSecretNamespace                               = "missing namespace of secret %s"
+	errFetchSecret                                          = "unable to fetch secret %s: %w"
+	errMissingSecretKey                                     = "key '%s' not found in secret '%s'"
+	errScopedProjectOverride                                = "%s overrides the project, which tokenScopedProject does not allow"
+	errPasscodeMismatch                                     = "passcode does not match secret encryption"
+	errNoSecretsMatched                                     = "no secrets matched filter"
 )
 
+// errorOnEmptyTag makes GetAllSecrets fail when no secret matches.
+const errorOnEmptyTag = "errorOnEmpty"
+
+// allSecretsKey is the remote key that makes GetSecret return the whole
+// environment as a single JSON object. An empty key behaves the same way.
+const allSecretsKey = "*"
+
+// defaultValidationProbes is how often Validate authenticates when the store
+// does not configure it.
+const defaultValidationProbes = 3
+
+// defaultMaxValueSize is the largest value PushSecret sends when the store
+// does not configure one.
+const defaultMaxValueSize = 64 * 1024
+
 type Client struct {
 	onboardbase         SecretsClientInterface
-	onboardbaseAPIKey    string
-	onboardbasePasscode    string
-	project         string
-	environment		string
+	onboardbaseAPIKey   string
+	onboardbasePasscode string
+	project             string
+	environment         string
+	parentEnvironment   string
+	tokenScopedProject  bool
+	trimSpace           bool
+	rawPayloads         bool
+	maxValueSize        int
+	pushMergeStrategy   esv1beta1.OnboardbasePushMergeStrategy
+	pushKeyPrefix       string
+	stopKeepalive       context.CancelFunc
+	authProbe           *authProbe
+	cache               *secretCache
+	keys                keyFilter
+	referencePrefix     string
+	validationProbes    int
+	snapshots           *secretSnapshots
+	readAfterWrite      *readAfterWrite
+	maxRefTimeout       time.Duration
+	log                 logr.Logger
 
 	kube      kclient.Client
 	store     *esv1beta1.OnboardbaseProvider
@@ -58,14 +107,35 @@ type Client struct {
 // SecretsClientInterface defines the required Onboardbase Client methods.
 type SecretsClientInterface interface {
 	BaseURL() *url.URL
-	Authenticate() error
-	GetSecret(request dClient.SecretRequest) (*dClient.SecretResponse, error)
-	GetSecrets(request dClient.SecretsRequest) (*dClient.SecretsResponse, error)
+	Authenticate(ctx context.Context) error
+	GetSecret(ctx context.Context, request dClient.SecretRequest) (*dClient.SecretResponse, error)
+	GetSecrets(ctx context.Context, request dClient.SecretsRequest) (*dClient.SecretsResponse, error)
+	GetSecretsByNames(ctx context.Context, request dClient.SecretsRequest, names []string) (map[string]*dClient.SecretResponse, error)
+	LastSuccessfulSync(project, environment string) (time.Time, bool)
+	UpdateSecrets(ctx context.Context, request dClient.UpdateSecretsRequest) error
+	DeleteSecrets(ctx context.Context, requests []dClient.SecretRequest) (*dClient.DeleteSecretsResponse, error)
+	Diagnose(ctx context.Context, project, environment string) *dClient.DiagnosticReport
+	VerifyPasscode(ctx context.Context, request dClient.SecretsRequest) (bool, error)
+	Status(ctx context.Context, project, environment string) dClient.StoreStatus
 }
 
+// Credentials read from files are not fetched here; the Onboardbase client
+// reads them itself so that it notices when they are rotated.
 func (c *Client) setAuth(ctx context.Context) error {
+	// The passcode only decrypts secrets on the client side.
+	needsPasscode := !c.store.ServerSideDecryption && c.store.Auth.OnboardbasePasscodeFile == ""
+	if c.store.Auth.OnboardbaseAPIKeyFile != "" && !needsPasscode {
+		return nil
+	}
+
+	// The passcode is read from the API key's Secret, unless the API key
+	// comes from a file.
+	credentialsSelector := c.store.Auth.OnboardbaseAPIKey
+	if c.store.Auth.OnboardbaseAPIKeyFile != "" {
+		credentialsSelector = c.store.Auth.OnboardbasePasscode
+	}
 	credentialsSecret := &corev1.Secret{}
-	credentialsSecretName := c.store.Auth.OnboardbaseAPIKey.Name
+	credentialsSecretName := credentialsSelector.Name
 	if credentialsSecretName == "" {
 		return fmt.Errorf(errOnboardbaseAPIKeySecretName)
 	}
@@ -75,10 +145,10 @@ func (c *Client) setAuth(ctx context.Context) error {
 	}
 	// only ClusterStore is allowed to set namespace (and then it's required)
 	if c.storeKind == esv1beta1.ClusterSecretStoreKind {
-		if c.store.Auth.OnboardbaseAPIKey.Namespace == nil {
+		if credentialsSelector.Namespace == nil {
 			return fmt.Errorf(errInvalidClusterStoreMissingOnboardbaseAPIKeyNamespace)
 		}
-		objectKey.Namespace = *c.store.Auth.OnboardbaseAPIKey.Namespace
+		objectKey.Namespace = *credentialsSelector.Namespace
 	}
 
 	err := c.kube.Get(ctx, objectKey, credentialsSecret)
@@ -86,12 +156,17 @@ func (c *Client) setAuth(ctx context.Context) error {
 		return fmt.Errorf(errFetchOnboardbaseAPIKeySecret, err)
 	}
 
-	onboardbaseAPIKey := credentialsSecret.Data[c.store.Auth.OnboardbaseAPIKey.Key]
-	if (onboardbaseAPIKey == nil) || (len(onboardbaseAPIKey) == 0) {
-		return fmt.Errorf(errMissingOnboardbaseAPIKey, c.store.Auth.OnboardbaseAPIKey.Key, credentialsSecretName)
+	if c.store.Auth.OnboardbaseAPIKeyFile == "" {
+		onboardbaseAPIKey := credentialsSecret.Data[c.store.Auth.OnboardbaseAPIKey.Key]
+		if (onboardbaseAPIKey == nil) || (len(onboardbaseAPIKey) == 0) {
+			return fmt.Errorf(errMissingOnboardbaseAPIKey, c.store.Auth.OnboardbaseAPIKey.Key, credentialsSecretName)
+		}
+		c.onboardbaseAPIKey = string(onboardbaseAPIKey)
 	}
-	c.onboardbaseAPIKey = string(onboardbaseAPIKey)
 
+	if !needsPasscode {
+		return nil
+	}
 
 	onboardbasePasscode := credentialsSecret.Data[c.store.Auth.OnboardbasePasscode.Key]
 	if (onboardbasePasscode == nil) || (len(onboardbasePasscode) == 0) {
@@ -103,6 +178,31 @@ func (c *Client) setAuth(ctx context.Context) error {
 	return nil
 }
 
+// getSecretKey returns the value referenced by selector. As with the API key,
+// only a ClusterSecretStore may reference another namespace.
+func (c *Client) getSecretKey(ctx context.Context, selector esmeta.SecretKeySelector) ([]byte, error) {
+	objectKey := types.NamespacedName{
+		Name:      selector.Name,
+		Namespace: c.namespace,
+	}
+	if c.storeKind == esv1beta1.ClusterSecretStoreKind {
+		if selector.Namespace == nil {
+			return nil, fmt.Errorf(errMissingSecretNamespace, selector.Name)
+		}
+		objectKey.Namespace = *selector.Namespace
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.kube.Get(ctx, objectKey, secret); err != nil {
+		return nil, fmt.Errorf(errFetchSecret, selector.Name, err)
+	}
+	value := secret.Data[selector.Key]
+	if len(value) == 0 {
+		return nil, fmt.Errorf(errMissingSecretKey, selector.Key, selector.Name)
+	}
+	return value, nil
+}
+
 func (c *Client) Validate() (esv1beta1.ValidationResult, error) {
 	timeout := 15 * time.Second
 	clientURL := c.onboardbase.BaseURL().String()
@@ -111,42 +211,344 @@ func (c *Client) Validate() (esv1beta1.ValidationResult, error) {
 		return esv1beta1.ValidationResultError, err
 	}
 
-	if err := c.onboardbase.Authenticate(); err != nil {
-		return esv1beta1.ValidationResultError, err
+	if c.authProbe != nil {
+		if err := c.authProbe.result(); err != nil {
+			return esv1beta1.ValidationResultError, err
+		}
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	result, err := c.probeAuthentication(ctx)
+	if result != esv1beta1.ValidationResultReady {
+		return result, err
+	}
+	return c.probePasscode(ctx)
+}
+
+// probePasscode decrypts a secret of the store's environment to tell a wrong
+// passcode, a configuration error, from an API that cannot be reached or
+// fails, whose status is unknown. There is nothing to check without
+// secrets or with server-side decryption.
+func (c *Client) probePasscode(ctx context.Context) (esv1beta1.ValidationResult, error) {
+	valid, err := c.onboardbase.VerifyPasscode(ctx, dClient.SecretsRequest{Project: c.project, Environment: c.environment})
+	switch {
+	case errors.Is(err, dClient.ErrVerifyServerSide), errors.Is(err, dClient.ErrNothingToVerify):
+		return esv1beta1.ValidationResultReady, nil
+	case err != nil:
+		c.logger().Info("unable to check the passcode", "error", err.Error())
+		return esv1beta1.ValidationResultUnknown, nil
+	case !valid:
+		return esv1beta1.ValidationResultError, errors.New(errPasscodeMismatch)
+	}
+	return esv1beta1.ValidationResultReady, nil
+}
+
+// probeAuthentication authenticates validationProbes times, so that a single
+// slow or failed request does not flip the store's status. It reports an
+// error when most probes fail, and an unknown result when only some do.
+// Probes still running when ctx is done fail.
+func (c *Client) probeAuthentication(ctx context.Context) (esv1beta1.ValidationResult, error) {
+	probes := c.validationProbes
+	if probes <= 0 {
+		probes = defaultValidationProbes
+	}
+
+	var failures int
+	var lastErr error
+	for i := 0; i < probes; i++ {
+		if err := c.onboardbase.Authenticate(ctx); err != nil {
+			failures++
+			lastErr = err
+		}
+		if failures > probes/2 {
+			return esv1beta1.ValidationResultError, fmt.Errorf(errValidationProbes, failures, i+1, lastErr)
+		}
+	}
+	if failures > 0 {
+		c.logger().Info("some validation probes failed", "failures", failures, "probes", probes, "error", lastErr.Error())
+		return esv1beta1.ValidationResultUnknown, nil
+	}
 	return esv1beta1.ValidationResultReady, nil
 }
 
+// Diagnose runs troubleshooting checks against the store's project and
+// environment. The report never contains secret values or credentials.
+func (c *Client) Diagnose(ctx context.Context) *dClient.DiagnosticReport {
+	return c.onboardbase.Diagnose(ctx, c.project, c.environment)
+}
+
+// VerifyPasscode reports whether the passcode decrypts a secret of the store's
+// environment, without keeping the decrypted value.
+func (c *Client) VerifyPasscode(ctx context.Context) (bool, error) {
+	return c.onboardbase.VerifyPasscode(ctx, dClient.SecretsRequest{Project: c.project, Environment: c.environment})
+}
+
+// Status summarizes the health of the store for dashboards. It is cached
+// briefly and never contains secret values.
+func (c *Client) Status(ctx context.Context) dClient.StoreStatus {
+	return c.onboardbase.Status(ctx, c.project, c.environment)
+}
+
 func (c *Client) DeleteSecret(ctx context.Context, remoteRef esv1beta1.PushRemoteRef) error {
-	return fmt.Errorf("not implemented")
+	response, err := c.DeleteSecrets(ctx, []esv1beta1.PushRemoteRef{remoteRef})
+	if err != nil {
+		return err
+	}
+	if err := response.Err(); err != nil {
+		return fmt.Errorf(errDeleteSecret, remoteRef.GetRemoteKey(), err)
+	}
+	return nil
+}
+
+// DeleteSecrets deletes several remote secrets in as few API calls as possible.
+// The returned response reports the outcome per key; on context cancellation it
+// lists the keys that were deleted before the operation was aborted.
+func (c *Client) DeleteSecrets(ctx context.Context, remoteRefs []esv1beta1.PushRemoteRef) (*dClient.DeleteSecretsResponse, error) {
+	requests := make([]dClient.SecretRequest, 0, len(remoteRefs))
+	for _, remoteRef := range remoteRefs {
+		requests = append(requests, dClient.SecretRequest{
+			Project:     c.project,
+			Environment: c.environment,
+			Name:        c.keys.remoteKey(remoteRef.GetRemoteKey()),
+		})
+	}
+
+	response, err := c.onboardbase.DeleteSecrets(ctx, requests)
+	c.snapshots.invalidate(c.project, c.environment)
+	if err != nil {
+		return response, fmt.Errorf(errDeleteSecrets, err)
+	}
+	return response, nil
 }
 
 func (c *Client) PushSecret(ctx context.Context, value []byte, remoteRef esv1beta1.PushRemoteRef) error {
-	return fmt.Errorf("not implemented")
+	if c.pushMergeStrategy == esv1beta1.OnboardbasePushMergeLocalWins || c.pushMergeStrategy == esv1beta1.OnboardbasePushMergeRemoteWins {
+		merged, err := c.mergeWithRemote(ctx, remoteRef.GetRemoteKey(), value)
+		if err != nil {
+			return fmt.Errorf(errPushSecret, remoteRef.GetRemoteKey(), err)
+		}
+		value = merged
+	}
+	return c.updateSecret(ctx, remoteRef.GetRemoteKey(), value)
+}
+
+// RotateSecret replaces the value of the secret at remoteRef with one produced
+// by generate and returns it. The new value is only returned once Onboardbase
+// accepted it, so callers update the cluster Secret after the remote one; when
+// generating or pushing fails, the previous value stays in effect on both sides.
+func (c *Client) RotateSecret(ctx context.Context, remoteRef esv1beta1.PushRemoteRef, generate func() ([]byte, error)) ([]byte, error) {
+	value, err := generate()
+	if err != nil {
+		return nil, fmt.Errorf(errRotateSecret, remoteRef.GetRemoteKey(), err)
+	}
+	if err := c.updateSecret(ctx, remoteRef.GetRemoteKey(), value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (c *Client) updateSecret(ctx context.Context, key string, value []byte) error {
+	if err := c.checkValueSize(value); err != nil {
+		return fmt.Errorf(errPushSecret, key, err)
+	}
+
+	request := dClient.UpdateSecretsRequest{
+		Project:     c.project,
+		Environment: c.environment,
+		Secrets: dClient.RawSecrets{
+			{Key: c.keys.remoteKey(key), Value: string(value)},
+		},
+	}
+
+	err := c.onboardbase.UpdateSecrets(ctx, request)
+	c.snapshots.invalidate(c.project, c.environment)
+	if err != nil {
+		return fmt.Errorf(errPushSecret, key, err)
+	}
+	if c.readAfterWrite != nil {
+		return c.readAfterWrite.waitVisible(ctx, c.onboardbase, dClient.SecretRequest{
+			Project:     c.project,
+			Environment: c.environment,
+			Name:        c.keys.remoteKey(key),
+		}, string(value))
+	}
+	return nil
+}
+
+// checkValueSize rejects values larger than the store allows.
+func (c *Client) checkValueSize(value []byte) error {
+	maxValueSize := c.maxValueSize
+	if maxValueSize <= 0 {
+		maxValueSize = defaultMaxValueSize
+	}
+	if len(value) > maxValueSize {
+		return fmt.Errorf(errValueTooLarge, len(value), maxValueSize)
+	}
+	return nil
+}
+
+// mergeWithRemote merges value into the current remote value of key, if any.
+func (c *Client) mergeWithRemote(ctx context.Context, key string, value []byte) ([]byte, error) {
+	remote, err := c.onboardbase.GetSecret(ctx, dClient.SecretRequest{
+		Project:     c.project,
+		Environment: c.environment,
+		Name:        c.keys.remoteKey(key),
+	})
+	if errors.Is(err, dClient.ErrSecretNotFound) {
+		return value, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mergeJSON(key, []byte(remote.Value), value, c.pushMergeStrategy)
 }
 
-func (c *Client) GetSecret(_ context.Context, ref esv1beta1.ExternalSecretDataRemoteRef) ([]byte, error) {
+// GetSecret returns the value of a single secret, or of ref.Property within it
+// when set. Properties use dot notation unless prefixed with jsonPointerPrefix.
+// With the Fetch metadata policy, it returns the secret's metadata instead.
+// A key of the form PROJECT:ENVIRONMENT:NAME overrides the store's project and
+// environment, unless the store sets tokenScopedProject; other keys
+// containing colons are taken as literal names. The assemble=dockerconfigjson
+// option builds a .dockerconfigjson from the registry credentials stored
+// under the key as prefix. The transform option, e.g.
+// transform=base64decode,property:db.password, runs named steps on the value
+// in order, before ref.Property is extracted. The timeout option, e.g.
+// timeout=90s, sets the deadline of the call, up to the store's maxRefTimeout.
+//
+// When the name is empty or allSecretsKey, it instead returns every secret of
+// the environment serialized as one JSON object mapping keys to values, with
+// keys in sorted order. This differs from GetSecretMap, which expands the JSON
+// held in a single value.
+func (c *Client) GetSecret(ctx context.Context, ref esv1beta1.ExternalSecretDataRemoteRef) ([]byte, error) {
+	name, opts, err := parseRemoteKey(ref.Key)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel, err := c.withRefTimeout(ctx, opts.timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	project, environment, name := parseCoordinates(name)
+
 	request := dClient.SecretRequest{
-		Project: c.project,
+		Project:     c.project,
 		Environment: c.environment,
-		Name: ref.Key,
+		Name:        name,
+		Headers:     opts.headers,
+	}
+	if project != "" {
+		if c.tokenScopedProject {
+			return nil, fmt.Errorf(errScopedProjectOverride, "remote key "+ref.Key)
+		}
+		request.Project = project
+		request.Environment = environment
+	}
+	if name == "" || name == allSecretsKey {
+		return c.getSecretsJSON(ctx, dClient.SecretsRequest{
+			Project:     request.Project,
+			Environment: request.Environment,
+		})
 	}
 
-	secret, err := c.onboardbase.GetSecret(request)
+	if ref.MetadataPolicy == esv1beta1.ExternalSecretMetadataPolicyFetch {
+		metadata, err := c.getSecretMetadata(ctx, request, ref.Property)
+		if err != nil {
+			return nil, fmt.Errorf(errGetSecret, name, err)
+		}
+		return metadata, nil
+	}
+	if opts.assemble == assembleDockerConfigJSON {
+		config, err := c.getDockerConfigJSON(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf(errGetSecret, name, err)
+		}
+		return config, nil
+	}
+
+	value, err := c.getSecretValue(ctx, request)
+	if errors.Is(err, dClient.ErrSecretNotFound) && opts.defaultValue != nil {
+		return []byte(*opts.defaultValue), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(errGetSecret, name, err)
+	}
+	value, err = c.resolveReferences(ctx, request, value)
+	if err != nil {
+		return nil, fmt.Errorf(errGetSecret, name, err)
+	}
+
+	if c.trimSpace {
+		value = bytes.TrimSpace(value)
+	}
+	value, err = opts.applyTransforms(name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref.Property == "" {
+		return value, nil
+	}
+	value, err = opts.toJSON(name, value)
 	if err != nil {
-		return nil, fmt.Errorf(errGetSecret, ref.Key, err)
+		return nil, err
+	}
+	return getProperty(value, name, ref.Property)
+}
+
+// getSecretValue fetches a single secret. Keys the store does not expose are
+// not found. With a cache configured, fetched values are written to it, and
+// served from it while the API is unavailable.
+func (c *Client) getSecretValue(ctx context.Context, request dClient.SecretRequest) ([]byte, error) {
+	if !c.keys.allows(request.Name) {
+		return nil, dClient.ErrSecretNotFound
+	}
+
+	secret, err := c.fetchSecret(ctx, request)
+	if c.cache == nil {
+		if err != nil {
+			return nil, err
+		}
+		return []byte(secret.Value), nil
 	}
 
+	// Stores may share the cache, so it is keyed by remote name.
+	key := cacheEntryKey(request.Project, request.Environment, c.keys.remoteKey(request.Name))
+	if err != nil {
+		if !dClient.IsUnavailable(err) {
+			return nil, err
+		}
+		cached, cacheErr := c.cache.get(ctx, key)
+		if cacheErr != nil {
+			return nil, fmt.Errorf("%w; no fallback from cache: %v", err, cacheErr)
+		}
+		c.logger().Info("serving cached secret while the API is unavailable", "key", request.Name, "error", err.Error())
+		return cached, nil
+	}
 
-	return []byte(secret.Value), nil
+	value := []byte(secret.Value)
+	if err := c.cache.put(ctx, key, value); err != nil {
+		c.logger().Error(err, "unable to cache secret", "key", request.Name)
+	}
+	return value, nil
 }
 
+// GetSecretMap expands a secret holding a JSON object, or a YAML mapping with
+// the yaml format option, into its fields.
 func (c *Client) GetSecretMap(ctx context.Context, ref esv1beta1.ExternalSecretDataRemoteRef) (map[string][]byte, error) {
+	name, opts, err := parseRemoteKey(ref.Key)
+	if err != nil {
+		return nil, err
+	}
 	data, err := c.GetSecret(ctx, ref)
 	if err != nil {
 		return nil, err
 	}
+	data, err = opts.toJSON(name, data)
+	if err != nil {
+		return nil, err
+	}
 
 	kv := make(map[string]json.RawMessage)
 	err = json.Unmarshal(data, &kv)
@@ -159,6 +561,9 @@ func (c *Client) GetSecretMap(ctx context.Context, ref esv1beta1.ExternalSecretD
 		var strVal string
 		err = json.Unmarshal(v, &strVal)
 		if err == nil {
+			if c.trimSpace {
+				strVal = strings.TrimSpace(strVal)
+			}
 			secretData[k] = []byte(strVal)
 		} else {
 			secretData[k] = v
@@ -167,14 +572,74 @@ func (c *Client) GetSecretMap(ctx context.Context, ref esv1beta1.ExternalSecretD
 	return secretData, nil
 }
 
+// GetAllSecrets returns the secrets of the environment matching ref. Keys are
+// returned verbatim, so path-like keys such as "config/app.yaml" keep their
+// slashes. The client only selects secrets by ref.Name and ref.Path; key
+// rewrites, conversionStrategy and decodingStrategy are applied afterwards by
+// the ExternalSecret controller and operate on the raw Onboardbase keys.
+//
+// The "projects" tag fetches the listed projects instead of the store's,
+// with keys prefixed by "<project>/". Projects that fail are skipped unless
+// the "strict" tag is true. Otherwise, the store's parentEnvironment, if any,
+// is fetched as well, and the environment's secrets override the parent's.
+// The "prefixEnvironment" tag then prefixes keys with "<environment>/", the
+// environment, or config, each value comes from. The "timeout" tag sets the
+// deadline of the call, up to the store's maxRefTimeout.
+//
+// Nothing matching ref yields an empty map, or an error with the
+// "errorOnEmpty" tag set to true, to catch a mistyped name or path.
 func (c *Client) GetAllSecrets(ctx context.Context, ref esv1beta1.ExternalSecretFind) (map[string][]byte, error) {
-	secrets, err := c.getSecrets(ctx)
-	selected := map[string][]byte{}
+	projects, strict, err := findProjects(ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) > 0 && c.tokenScopedProject {
+		return nil, fmt.Errorf(errScopedProjectOverride, "the "+projectsTag+" tag")
+	}
+	prefixEnvironment, err := findBoolTag(ref, environmentPrefixTag)
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) > 0 && prefixEnvironment {
+		return nil, fmt.Errorf(errTagsNotCombinable, environmentPrefixTag, projectsTag)
+	}
+	errorOnEmpty, err := findBoolTag(ref, errorOnEmptyTag)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := findTimeout(ref)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel, err := c.withRefTimeout(ctx, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
 
+	var secrets map[string][]byte
+	if len(projects) > 0 {
+		secrets, err = c.getProjectsSecrets(ctx, projects, strict)
+	} else {
+		secrets, err = c.getSecrets(ctx, prefixEnvironment)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := selectSecrets(secrets, ref)
 	if err != nil {
 		return nil, err
 	}
+	if len(selected) == 0 && errorOnEmpty {
+		return nil, errors.New(errNoSecretsMatched)
+	}
+	return selected, nil
+}
 
+// selectSecrets returns the secrets whose key matches ref.Name and starts
+// with ref.Path, when set.
+func selectSecrets(secrets map[string][]byte, ref esv1beta1.ExternalSecretFind) (map[string][]byte, error) {
 	if ref.Name == nil && ref.Path == nil {
 		return secrets, nil
 	}
@@ -188,33 +653,116 @@ func (c *Client) GetAllSecrets(ctx context.Context, ref esv1beta1.ExternalSecret
 		matcher = m
 	}
 
+	selected := map[string][]byte{}
 	for key, value := range secrets {
 		if (matcher != nil && !matcher.MatchName(key)) || (ref.Path != nil && !strings.HasPrefix(key, *ref.Path)) {
 			continue
 		}
 		selected[key] = value
 	}
-
 	return selected, nil
 }
 
+// LastSuccessfulSync returns when the store's secrets were last fetched
+// successfully, so that it can be surfaced on the store or ExternalSecret.
+func (c *Client) LastSuccessfulSync() (time.Time, bool) {
+	return c.onboardbase.LastSuccessfulSync(c.project, c.environment)
+}
+
 func (c *Client) Close(_ context.Context) error {
+	if c.stopKeepalive != nil {
+		c.stopKeepalive()
+	}
 	return nil
 }
 
-func (c *Client) getSecrets(_ context.Context) (map[string][]byte, error) {
-	request := dClient.SecretsRequest{
-		Project:         c.project,
+// getSecrets returns the secrets of the store's environment, merged over
+// those of its parent environment if any. With prefixEnvironment, keys are
+// prefixed with the environment their value comes from.
+func (c *Client) getSecrets(ctx context.Context, prefixEnvironment bool) (map[string][]byte, error) {
+	if c.parentEnvironment != "" {
+		return c.getInheritedSecrets(ctx, prefixEnvironment)
+	}
+	secrets, err := c.getEnvironmentSecrets(ctx, dClient.SecretsRequest{
+		Project:     c.project,
 		Environment: c.environment,
+	})
+	if err != nil || !prefixEnvironment {
+		return secrets, err
+	}
+	prefixed := make(map[string][]byte, len(secrets))
+	for key, value := range secrets {
+		prefixed[environmentKey(c.environment, key, true)] = value
 	}
+	return prefixed, nil
+}
 
-	response, err := c.onboardbase.GetSecrets(request)
+// getEnvironmentSecrets returns the filtered secrets of the environment
+// identified by request.
+func (c *Client) getEnvironmentSecrets(ctx context.Context, request dClient.SecretsRequest) (map[string][]byte, error) {
+	response, err := c.onboardbase.GetSecrets(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf(errGetSecrets, err)
 	}
+	c.logFetch(request, response)
+
+	return c.keys.filter(externalSecretsFormat(response.Secrets)), nil
+}
+
+// logFetch records, for audit trails, that the secrets of an environment were
+// fetched. The payload is identified by its fingerprint, never its content.
+func (c *Client) logFetch(request dClient.SecretsRequest, response *dClient.SecretsResponse) {
+	c.logger().V(1).Info("fetched secrets", "project", request.Project, "environment", request.Environment, "fingerprint", response.Fingerprint())
+}
+
+func (c *Client) getSecretsJSON(ctx context.Context, request dClient.SecretsRequest) ([]byte, error) {
+	if c.rawPayloads {
+		return c.getRawPayloadsJSON(ctx, request)
+	}
+
+	secrets, err := c.getEnvironmentSecrets(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(secrets))
+	for key, value := range secrets {
+		if c.trimSpace {
+			value = bytes.TrimSpace(value)
+		}
+		values[key] = string(value)
+	}
+
+	// encoding/json sorts map keys, which keeps the output deterministic.
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalSecrets, err)
+	}
+	return data, nil
+}
 
+// getRawPayloadsJSON returns the decrypted payloads of the environment as a
+// JSON array of strings, in the order the API returned them. It refuses to
+// when the store restricts keys, since payloads cannot be filtered.
+func (c *Client) getRawPayloadsJSON(ctx context.Context, request dClient.SecretsRequest) ([]byte, error) {
+	if c.keys.restricts() {
+		return nil, errors.New(errRawPayloadsFiltered)
+	}
+	response, err := c.onboardbase.GetSecrets(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf(errGetSecrets, err)
+	}
+	c.logFetch(request, response)
 
-	return externalSecretsFormat(response.Secrets), nil
+	payloads := response.RawPayloads
+	if payloads == nil {
+		payloads = []string{}
+	}
+	data, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalSecrets, err)
+	}
+	return data, nil
 }
 
 func externalSecretsFormat(secrets dClient.Secrets) map[string][]byte {
@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	// DefaultBackoffBase and DefaultBackoffMax configure the backoff used
+	// when MaxRetries is set without a Backoff.
+	DefaultBackoffBase = 200 * time.Millisecond
+	DefaultBackoffMax  = 5 * time.Second
+)
+
+// Backoff computes how long to wait before retrying a failed request.
+type Backoff interface {
+	// Delay returns the wait before retry n, counting from 1.
+	Delay(n int) time.Duration
+}
+
+// ConstantBackoff waits the same interval before every retry.
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+func (b ConstantBackoff) Delay(_ int) time.Duration {
+	return b.Interval
+}
+
+// LinearBackoff waits Base times the retry number, up to Max.
+type LinearBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (b LinearBackoff) Delay(n int) time.Duration {
+	return capDelay(b.Base*time.Duration(n), b.Max)
+}
+
+// ExponentialBackoff doubles the wait with every retry, starting at Base and
+// up to Max. Half of each wait is randomized, so that clients failing at the
+// same time do not retry in lockstep.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+	// random returns a number in [0, 1). Defaults to math/rand.
+	random func() float64
+}
+
+func (b ExponentialBackoff) Delay(n int) time.Duration {
+	d := b.Base
+	for i := 1; i < n && (b.Max <= 0 || d < b.Max); i++ {
+		d *= 2
+	}
+	d = capDelay(d, b.Max)
+
+	random := b.random
+	if random == nil {
+		random = rand.Float64 //nolint:gosec // jitter needs no cryptographic randomness
+	}
+	half := d / 2
+	return half + time.Duration(random()*float64(d-half))
+}
+
+func capDelay(d, limit time.Duration) time.Duration {
+	if limit > 0 && d > limit {
+		return limit
+	}
+	return d
+}
+
+func (c *OnboardbaseClient) backoff() Backoff {
+	if c.Backoff == nil {
+		return ExponentialBackoff{Base: DefaultBackoffBase, Max: DefaultBackoffMax}
+	}
+	return c.Backoff
+}
+
+// sleep waits for d on the client's clock, or until ctx is done.
+func (c *OnboardbaseClient) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.Clock.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+// ChangedSecrets are the secrets of an environment that changed since a
+// previous fetch.
+type ChangedSecrets struct {
+	// Secrets are the keys that are new or whose value changed, with their
+	// current value.
+	Secrets Secrets
+	// Removed are the keys of the previous fetch, in sorted order, that the
+	// environment no longer holds.
+	Removed []string
+	// Fingerprints identify the current value of every key, to be passed as
+	// previous to the next call. They are hashes, so callers need not keep
+	// the values themselves.
+	Fingerprints map[string]string
+}
+
+// GetChangedSecrets fetches the secrets of an environment and returns those
+// whose value differs from the fingerprints in previous, as returned by an
+// earlier call, so that callers can skip rewriting unchanged data. Values are
+// compared after decryption, so re-encrypting a secret does not change it.
+// With no previous fingerprints every secret is returned.
+func (c *OnboardbaseClient) GetChangedSecrets(ctx context.Context, request SecretsRequest, previous map[string]string) (*ChangedSecrets, error) {
+	if request.AllEnvironments {
+		return nil, &APIError{Message: "cannot get changed secrets across all environments"}
+	}
+	if c.RawPayloads {
+		return nil, &APIError{Message: "cannot get changed secrets while raw payloads are enabled"}
+	}
+
+	response, err := c.getSecrets(ctx, request)
+	if err != nil {
+		return nil, &APIError{Err: err, Message: fmt.Sprintf("unable to fetch secrets of project '%s' and environment '%s'", request.Project, request.Environment)}
+	}
+
+	changed := &ChangedSecrets{
+		Secrets:      Secrets{},
+		Fingerprints: make(map[string]string, len(response.Secrets)),
+	}
+	for key, value := range response.Secrets {
+		fingerprint := secretFingerprint(key, value)
+		changed.Fingerprints[key] = fingerprint
+		if previous[key] != fingerprint {
+			changed.Secrets[key] = value
+		}
+	}
+	for key := range previous {
+		if _, ok := response.Secrets[key]; !ok {
+			changed.Removed = append(changed.Removed, key)
+		}
+	}
+	sort.Strings(changed.Removed)
+	return changed, nil
+}
+
+// secretFingerprint hashes a value together with its key, so that equal
+// values of different keys have different fingerprints.
+func secretFingerprint(key, value string) string {
+	sum := sha256.Sum256([]byte(key + "\x00" + value))
+	return fingerprintPrefix + hex.EncodeToString(sum[:])
+}
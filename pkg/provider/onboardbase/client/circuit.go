@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is wrapped by the error returned for a request that was not
+// sent because the circuit of its operation is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Operation groups requests by risk profile, so that failing writes do not
+// stop reads and the other way around. Each has its own circuit.
+type Operation string
+
+const (
+	OperationRead  Operation = "read"
+	OperationWrite Operation = "write"
+)
+
+func operationOf(method string) Operation {
+	if method == http.MethodGet {
+		return OperationRead
+	}
+	return OperationWrite
+}
+
+// CircuitState is the state of the circuit of an operation.
+type CircuitState string
+
+const (
+	// CircuitClosed lets requests through.
+	CircuitClosed CircuitState = "Closed"
+	// CircuitOpen fails requests without sending them.
+	CircuitOpen CircuitState = "Open"
+	// CircuitHalfOpen lets a single request through to probe the API.
+	CircuitHalfOpen CircuitState = "HalfOpen"
+)
+
+// circuitStateValues are the values of the circuit state metric.
+var circuitStateValues = map[CircuitState]float64{CircuitClosed: 0, CircuitHalfOpen: 1, CircuitOpen: 2}
+
+// CircuitSettings configure the circuit breaker of an operation. After
+// Threshold consecutive requests failed because the API was unavailable,
+// the circuit opens and requests fail immediately for Cooldown. Then a
+// single request is let through, closing the circuit when it succeeds and
+// reopening it when it does not. Disabled when Threshold is zero.
+type CircuitSettings struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+type circuit struct {
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuits() map[Operation]*circuit {
+	return map[Operation]*circuit{
+		OperationRead:  {state: CircuitClosed},
+		OperationWrite: {state: CircuitClosed},
+	}
+}
+
+// allow reports whether a request may be sent, moving an open circuit whose
+// cooldown elapsed to half-open for the request to probe the API.
+func (b *circuit) allow(settings CircuitSettings, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < settings.Cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the circuit with the outcome of a request it allowed.
+// Requests that were cancelled tell nothing about the API, so a cancelled
+// probe only lets the next request probe instead.
+func (b *circuit) record(settings CircuitSettings, failed, cancelled bool, now time.Time) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case cancelled:
+		if b.state == CircuitHalfOpen {
+			b.state = CircuitOpen
+		}
+	case !failed:
+		b.state = CircuitClosed
+		b.failures = 0
+	case b.state == CircuitHalfOpen:
+		b.state = CircuitOpen
+		b.openedAt = now
+	default:
+		b.failures++
+		if b.failures >= settings.Threshold {
+			b.state = CircuitOpen
+			b.openedAt = now
+		}
+	}
+	return b.state
+}
+
+func (b *circuit) get() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (c *OnboardbaseClient) circuitSettings(operation Operation) CircuitSettings {
+	if operation == OperationRead {
+		return c.ReadCircuit
+	}
+	return c.WriteCircuit
+}
+
+// CircuitState returns the state of the circuit of an operation.
+func (c *OnboardbaseClient) CircuitState(operation Operation) CircuitState {
+	if b, ok := c.circuits[operation]; ok {
+		return b.get()
+	}
+	return CircuitClosed
+}
+
+// allowRequest checks the circuit of the request's operation, returning an
+// error wrapping ErrCircuitOpen when the request must not be sent.
+func (c *OnboardbaseClient) allowRequest(method, path string) error {
+	operation := operationOf(method)
+	settings := c.circuitSettings(operation)
+	if settings.Threshold <= 0 {
+		return nil
+	}
+	if c.circuits[operation].allow(settings, c.Clock.Now()) {
+		return nil
+	}
+	circuitRejections.WithLabelValues(c.BaseURL().Host, string(operation)).Inc()
+	return &APIError{
+		Err:       ErrCircuitOpen,
+		Message:   fmt.Sprintf("not sending %s request while the API keeps failing %s requests", operation, operation),
+		Operation: method + " " + path,
+	}
+}
+
+// recordRequest updates the circuit of the request's operation with its
+// outcome.
+func (c *OnboardbaseClient) recordRequest(method string, err, ctxErr error) {
+	operation := operationOf(method)
+	settings := c.circuitSettings(operation)
+	if settings.Threshold <= 0 {
+		return
+	}
+	state := c.circuits[operation].record(settings, IsUnavailable(err), ctxErr != nil, c.Clock.Now())
+	circuitState.WithLabelValues(c.BaseURL().Host, string(operation)).Set(circuitStateValues[state])
+}
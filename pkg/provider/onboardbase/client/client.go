@@ -16,27 +16,175 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	aesdecrypt "github.com/Onboardbase/go-cryptojs-aes-decrypt/decrypt"
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
 )
 
+// OnboardbaseClient is safe for concurrent use. Its exported fields,
+// SetCipherSuites, SetResponseHeaderTimeout, SetGraphQL and StartKeepalive
+// configure it before it is shared; SetBaseURL, SetReadURL, SetFailoverURLs and SetCredentialFiles
+// may be called while requests are in flight.
 type OnboardbaseClient struct {
-	baseURL      *url.URL
-	OnboardbaseAPIKey string
-	VerifyTLS    bool
-	UserAgent    string
+	// mu guards the API URLs and the credential files.
+	mu                  sync.RWMutex
+	baseURL             *url.URL
+	readURL             *url.URL
+	failoverURLs        []*url.URL
+	OnboardbaseAPIKey   string
+	VerifyTLS           bool
+	UserAgent           string
 	OnboardbasePassCode string
-	httpClient *http.Client
+	// SecretKeyField and SecretValueField name the fields of a decrypted
+	// secret object holding its key and value.
+	SecretKeyField   string
+	SecretValueField string
+	// AcceptLanguage is sent as the Accept-Language header, so that error
+	// messages of the API come in a consistent language. Defaults to
+	// DefaultAcceptLanguage; the header is omitted when empty.
+	AcceptLanguage string
+	// ExtraHeaders are sent with every request.
+	ExtraHeaders map[string]string
+	// DuplicateKeyPolicy decides which value is kept when a response holds
+	// the same key more than once. Defaults to DuplicateKeysLastWins.
+	DuplicateKeyPolicy DuplicateKeyPolicy
+	// FallbackPasscodes are tried in order on secrets the passcode does not
+	// decrypt, e.g. while secrets are re-encrypted after the passcode was
+	// rotated. Each use is counted and logged.
+	FallbackPasscodes []string
+	// EncryptPushedSecrets makes UpdateSecrets encrypt each secret with the
+	// passcode, in the format the API returns secrets in, instead of sending
+	// plaintext.
+	EncryptPushedSecrets bool
+	// CompressThreshold makes UpdateSecrets gzip request bodies of at least
+	// this many bytes, sent with Content-Encoding: gzip. If the API rejects
+	// the encoding with a 415 response, the request is sent again
+	// uncompressed and compression stays off. Disabled when zero.
+	CompressThreshold int
+	// TokenScopedProject leaves the project out of query parameters, for API
+	// keys scoped to a single project that the API applies on its own.
+	TokenScopedProject bool
+	// TraceRequests logs every request at verbosity 2 as an equivalent curl
+	// command, to reproduce failing calls by hand. The API key and passcodes
+	// are redacted, but request bodies may hold pushed secret values.
+	TraceRequests bool
+	// ServerSideDecryption means the API returns secrets as plaintext JSON
+	// objects, so they are not decrypted with OnboardbasePassCode.
+	ServerSideDecryption bool
+	// StrictDecode rejects secret responses carrying fields unknown to the
+	// client, to surface changes of the API schema.
+	StrictDecode bool
+	// RawPayloads makes GetSecrets return the decrypted payloads as they
+	// are instead of parsing them as key/value objects. It is an escape
+	// hatch for secrets that do not have the expected shape.
+	RawPayloads bool
+	// AllowEmptyValues makes GetSecret return secrets whose value is empty.
+	// By default they are reported as not found.
+	AllowEmptyValues bool
+	// SigningKey, when set, signs every request for gateways that require
+	// it: an HMAC-SHA256 over the method, URL path, query, timestamp and
+	// body, sent in SigningHeader, or DefaultSigningHeader when that is
+	// empty. The timestamp is sent in SigningTimestampHeader. Requests whose
+	// body exceeds SigningMaxBodySize, or DefaultSigningMaxBodySize when
+	// zero, are rejected rather than signed.
+	SigningKey         []byte
+	SigningHeader      string
+	SigningMaxBodySize int
+	// MaxRetries is how often a request that could fail over is retried,
+	// across all hosts, once they all failed. Retries wait according to
+	// Backoff, which defaults to an ExponentialBackoff.
+	MaxRetries int
+	Backoff    Backoff
+	// RetryableStatusCodes are response status codes retried in addition to
+	// the defaults: 429, every 5xx, network errors and open circuits.
+	RetryableStatusCodes []int
+	// HedgeDelay enables request hedging for reads: a GET that has not
+	// completed after this delay is sent a second time, and the first
+	// successful response is used. Disabled when zero.
+	HedgeDelay time.Duration
+	// BodyReadTimeout aborts a request whose response body is not read
+	// within this duration of receiving its headers, so that a stalled
+	// stream fails before the overall request timeout. Disabled when zero.
+	BodyReadTimeout time.Duration
+	// SuccessCodes declares, per operation such as "POST /secrets", the
+	// statuses a successful response may have. Any other status fails the
+	// request, even a 2xx, to catch requests misrouted by a proxy.
+	// Operations not listed accept any 2xx or 3xx status.
+	SuccessCodes map[string][]int
+	// PayloadHosts are the hosts, besides the API host, that a pre-signed
+	// URL returned in place of secrets may point to.
+	PayloadHosts []string
+	// ReadCircuit and WriteCircuit configure independent circuit breakers
+	// for GET requests and for all other requests.
+	ReadCircuit  CircuitSettings
+	WriteCircuit CircuitSettings
+	// Log receives the logs of the client. Defaults to the provider's
+	// logger.
+	Log          logr.Logger
+	Clock        Clock
+	httpClient   *http.Client
+	rateLimiter  *rateLimiter
+	syncTracker  *syncTracker
+	circuits     map[Operation]*circuit
+	fetcher      secretsFetcher
+	apiKeyFile   *credentialFile
+	passCodeFile *credentialFile
+
+	// compressionRejected is set once the API rejected a gzip body.
+	compressionRejected atomic.Bool
+	statuses            statusCache
 }
 
+// DuplicateKeyPolicy is a strategy for secrets returned more than once.
+type DuplicateKeyPolicy string
+
+const (
+	DuplicateKeysLastWins  DuplicateKeyPolicy = "LastWins"
+	DuplicateKeysFirstWins DuplicateKeyPolicy = "FirstWins"
+	DuplicateKeysError     DuplicateKeyPolicy = "Error"
+)
+
+var log = ctrl.Log.WithName("provider").WithName("onboardbase")
+
+// HeaderAPIKey is the request header carrying the API key.
+const HeaderAPIKey = "api_key"
+
+// DefaultAcceptLanguage is the language error messages are requested in.
+const DefaultAcceptLanguage = "en"
+
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+	headerRequestID      = "X-Request-Id"
+)
+
+const (
+	defaultSecretKeyField   = "key"
+	defaultSecretValueField = "value"
+	updatedAtField          = "updatedAt"
+)
+
+// EnvironmentKeySeparator separates the environment from the secret key when
+// secrets of several environments are returned together.
+const EnvironmentKeySeparator = "/"
+
 type queryParams map[string]string
 
 type headers map[string]string
@@ -46,16 +194,35 @@ type httpRequestBody []byte
 type Secrets map[string]string
 
 type RawSecret struct {
-	Key string `json:"key,omitempty"`
+	Key   string `json:"key,omitempty"`
 	Value string `json:"value,omitempty"`
+	// UpdatedAt is when the secret was last changed, if the API reports it.
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
 }
 
 type RawSecrets []RawSecret
 
+// ErrSecretNotFound is wrapped by the error GetSecret returns when the API
+// has no secret with the requested name.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// ErrEnvironmentNotFound is wrapped by the error returned when the API
+// answers 404 for the secrets of an environment, which usually means the
+// project or environment is misconfigured. A secret missing from an
+// existing environment is reported with ErrSecretNotFound instead.
+var ErrEnvironmentNotFound = errors.New("project or environment not found")
+
 type APIError struct {
 	Err     error
 	Message string
 	Data    string
+	// StatusCode is the HTTP status of an unsuccessful response, or zero
+	// when no response was received.
+	StatusCode int
+	// RequestID is the server-assigned ID of the failed request, if any.
+	RequestID string
+	// Operation is the method and path of the failed request, e.g. "GET /secrets".
+	Operation string
 }
 
 type apiResponse struct {
@@ -69,53 +236,120 @@ type apiErrorResponse struct {
 }
 
 type SecretRequest struct {
-	Environment    string
-	Project string
-	Name    string
+	Environment string
+	Project     string
+	Name        string
+	// Headers are sent with this request only. They take precedence over
+	// ExtraHeaders but cannot replace the api_key header.
+	Headers map[string]string
 }
 
 type SecretsRequest struct {
-	Environment    string
-	Project string
+	Environment string
+	Project     string
+	// AllEnvironments fetches the secrets of every environment in the project.
+	// Environment must be empty; returned keys are namespaced as
+	// "<environment>/<key>" so that equal keys do not collide.
+	AllEnvironments bool
+	// ModifiedSince only returns secrets changed after the given time.
+	// Ignored when zero.
+	ModifiedSince time.Time
+	// Format, when set, makes GetSecrets render the secrets into the Body
+	// of the response in that format.
+	Format SecretsFormat
 }
 
+// UpdateSecretsRequest is the body of the update endpoint, which names the
+// environment config.
 type UpdateSecretsRequest struct {
-	Secrets RawSecrets `json:"secrets,omitempty"`
-	Project string     `json:"project,omitempty"`
-	Config  string     `json:"config,omitempty"`
+	Secrets     RawSecrets `json:"secrets,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	Environment string     `json:"config,omitempty"`
+}
+
+type DeleteSecretsRequest struct {
+	Project     string   `json:"project,omitempty"`
+	Environment string   `json:"environment,omitempty"`
+	Secrets     []string `json:"secrets,omitempty"`
+}
+
+// DeleteSecretResult is the outcome of deleting a single secret as part of a batch.
+type DeleteSecretResult struct {
+	Request SecretRequest
+	Err     error
+}
+
+// DeleteSecretsResponse holds the per-secret outcomes of DeleteSecrets.
+type DeleteSecretsResponse struct {
+	Results []DeleteSecretResult
 }
 
 type secretResponseBodyObject struct {
 	Title string `json:"title,omitempty"`
-	Id string `json:"id,omitempty"`
+	Id    string `json:"id,omitempty"`
 }
 
 type secretResponseBodyData struct {
-	Project secretResponseBodyObject `json:"project,omitempty"`
+	Project     secretResponseBodyObject `json:"project,omitempty"`
 	Environment secretResponseBodyObject `json:"environment,omitempty"`
-	Team secretResponseBodyObject `json:"team,omitempty"`
-	Secrets []string `json:"secrets,omitempty"`
+	Team        secretResponseBodyObject `json:"team,omitempty"`
+	Secrets     []string                 `json:"secrets,omitempty"`
+	// SecretsURL is a pre-signed URL serving Secrets, for responses that do
+	// not include them inline.
+	SecretsURL string `json:"secretsUrl,omitempty"`
 }
 
 type secretResponseBody struct {
-	Data secretResponseBodyData `json:"data,omitempty"`
-	Message string `json:"message,omitempty"`
-	Status string `json:"status,omitempty"`
+	Data    secretResponseBodyData `json:"data,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Status  string                 `json:"status,omitempty"`
+}
+
+type projectSecretsResponseBody struct {
+	Data    []secretResponseBodyData `json:"data,omitempty"`
+	Message string                   `json:"message,omitempty"`
+	Status  string                   `json:"status,omitempty"`
+}
+
+type projectResponseBodyData struct {
+	secretResponseBodyObject
+	Environments []secretResponseBodyObject `json:"environments,omitempty"`
+}
+
+type projectsResponseBody struct {
+	Data    []projectResponseBodyData `json:"data,omitempty"`
+	Message string                    `json:"message,omitempty"`
+	Status  string                    `json:"status,omitempty"`
+}
+
+// Project is an Onboardbase project and the titles of its environments.
+type Project struct {
+	Title        string
+	Environments []string
 }
 
 type SecretResponse struct {
 	Name  string
 	Value string
+	// UpdatedAt is when the secret was last changed. It is nil when the API
+	// does not report it.
+	UpdatedAt *time.Time
 }
 
 type SecretsResponse struct {
-	Secrets  Secrets
-	Body     []byte
+	Secrets Secrets
+	// RawPayloads holds the decrypted secret payloads, unparsed, when the
+	// client has RawPayloads set. Secrets is empty then.
+	RawPayloads []string
+	// Body is the response of the API, or the secrets in the requested
+	// Format.
+	Body []byte
+	// apiBody is the response of the API when Body was rendered.
+	apiBody []byte
 }
 
 func NewOnboardbaseClient(onboardbaseAPIKey, onboardbasePasscode string) (*OnboardbaseClient, error) {
 
-
 	tlsConfig := &tls.Config{
 		MinVersion: tls.VersionTLS12,
 	}
@@ -124,17 +358,25 @@ func NewOnboardbaseClient(onboardbaseAPIKey, onboardbasePasscode string) (*Onboa
 		TLSClientConfig:   tlsConfig,
 	}
 	client := &OnboardbaseClient{
-		OnboardbaseAPIKey: onboardbaseAPIKey,
+		OnboardbaseAPIKey:   onboardbaseAPIKey,
 		OnboardbasePassCode: onboardbasePasscode,
-		VerifyTLS:    true,
-		UserAgent:    "onboardbase-external-secrets",
+		VerifyTLS:           true,
+		UserAgent:           "onboardbase-external-secrets",
+		AcceptLanguage:      DefaultAcceptLanguage,
+		SecretKeyField:      defaultSecretKeyField,
+		SecretValueField:    defaultSecretValueField,
+		DuplicateKeyPolicy:  DuplicateKeysLastWins,
+		Clock:               realClock{},
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-			Transport: httpTransport,
+			Timeout:       10 * time.Second,
+			Transport:     httpTransport,
+			CheckRedirect: checkRedirect,
 		},
+		rateLimiter: newRateLimiter(),
+		circuits:    newCircuits(),
+		syncTracker: newSyncTracker(),
 	}
 
-
 	if err := client.SetBaseURL("https://public.onboardbase.com/api/v1/"); err != nil {
 		return nil, &APIError{Err: err, Message: "setting base URL failed"}
 	}
@@ -143,6 +385,8 @@ func NewOnboardbaseClient(onboardbaseAPIKey, onboardbasePasscode string) (*Onboa
 }
 
 func (c *OnboardbaseClient) BaseURL() *url.URL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	u := *c.baseURL
 	return &u
 }
@@ -158,102 +402,538 @@ func (c *OnboardbaseClient) SetBaseURL(urlStr string) error {
 		baseURL.Scheme = "https"
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.baseURL = baseURL
 	return nil
 }
 
-func (c *OnboardbaseClient) Authenticate() error {
+func (c *OnboardbaseClient) Authenticate(ctx context.Context) error {
+	return c.ping(ctx)
+}
 
-	if _, err := c.performRequest("/team/members", "GET", headers{}, queryParams{	}, httpRequestBody{}); err != nil {
-		return err
+// decodeResponse unmarshals a secrets response body into v. Unless
+// StrictDecode is set, known variations of the envelope are normalized
+// first; with it, unknown fields are rejected instead.
+func (c *OnboardbaseClient) decodeResponse(body []byte, v *secretResponseBody) error {
+	if !c.StrictDecode {
+		return json.Unmarshal(normalizeEnvelope(body), v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("strict decoding failed, the API response schema may have changed: %w", err)
 	}
-
 	return nil
 }
 
-func (c *OnboardbaseClient) getSecretsFromPayload(data secretResponseBodyData) (map[string]string, error) {
-	kv := make(map[string]string)
+// getDecryptedRaw decrypts the secrets of a payload without interpreting
+// them, in the order the API returned them.
+func (c *OnboardbaseClient) getDecryptedRaw(data secretResponseBodyData) ([]string, error) {
+	raw := make([]string, 0, len(data.Secrets))
+	var decryptErr error
 	for _, secret := range data.Secrets {
-		passphrase := c.OnboardbasePassCode
-		decrypted, err := aesdecrypt.Run(secret, passphrase)
+		if c.ServerSideDecryption {
+			raw = append(raw, secret)
+			continue
+		}
+		decrypted, err := c.decryptWithFallback(data, secret)
 		if err != nil {
-			return nil, &APIError{Err: err, Message: "unable to decrypt secret payload", Data: secret}
+			// Every secret is tried so that each failing one is recorded.
+			c.recordDecryptFailure(data, secret, err)
+			if decryptErr == nil {
+				decryptErr = &APIError{Err: err, Message: fmt.Sprintf("unable to decrypt secret payload %s", secretID(secret)), Data: secret}
+			}
+			continue
 		}
-		var decryptedJSON RawSecret
-		if err := json.Unmarshal([]byte(decrypted), &decryptedJSON); err != nil {
-		    return nil, &APIError{Err: err, Message: "unable to unmarshal secret payload", Data: decrypted}
-	    }
-		kv[decryptedJSON.Key] = decryptedJSON.Value
+		raw = append(raw, decrypted)
+	}
+	if decryptErr != nil {
+		return nil, decryptErr
+	}
+	return raw, nil
+}
+
+func (c *OnboardbaseClient) getSecretsFromPayload(data secretResponseBodyData) (map[string]string, error) {
+	entries, err := c.getSecretEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	kv := make(map[string]string, len(entries))
+	for key, entry := range entries {
+		kv[key] = entry.Value
+	}
+	return kv, nil
+}
+
+// getSecretEntries decrypts and parses the secrets of a payload, applying
+// the DuplicateKeyPolicy.
+func (c *OnboardbaseClient) getSecretEntries(data secretResponseBodyData) (map[string]RawSecret, error) {
+	raw, err := c.getDecryptedRaw(data)
+	if err != nil {
+		return nil, err
+	}
+	kv := make(map[string]RawSecret)
+	for _, decrypted := range raw {
+		decryptedJSON, err := c.parseRawSecret(decrypted)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := kv[decryptedJSON.Key]; exists {
+			switch c.DuplicateKeyPolicy {
+			case DuplicateKeysError:
+				return nil, &APIError{Message: fmt.Sprintf("secret %s appears more than once in environment '%s'", decryptedJSON.Key, data.Environment.Title)}
+			case DuplicateKeysFirstWins:
+				c.logger().Info("ignoring duplicate secret key", "key", decryptedJSON.Key, "project", data.Project.Title, "environment", data.Environment.Title)
+				continue
+			default:
+				c.logger().Info("overwriting duplicate secret key", "key", decryptedJSON.Key, "project", data.Project.Title, "environment", data.Environment.Title)
+			}
+		}
+		kv[decryptedJSON.Key] = decryptedJSON
 	}
 	return kv, nil
 }
 
-func (c *OnboardbaseClient) GetSecret(request SecretRequest) (*SecretResponse, error) {
-	params := request.buildQueryParams()
+// parseRawSecret maps a decrypted secret object to a RawSecret. The configured
+// field names are tried first, then the default "key" and "value" fields.
+func (c *OnboardbaseClient) parseRawSecret(decrypted string) (RawSecret, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(decrypted), &fields); err != nil {
+		message := fmt.Sprintf("secret payload is not a JSON object: expected fields %q and %q, found %s",
+			c.SecretKeyField, c.SecretValueField, jsonKind(decrypted))
+		return RawSecret{}, &APIError{Err: err, Message: message, Data: truncateData(decrypted)}
+	}
+
+	keyField := lookupField(fields, c.SecretKeyField, defaultSecretKeyField)
+	valueField := lookupField(fields, c.SecretValueField, defaultSecretValueField)
+	if keyField == "" || valueField == "" {
+		found := make([]string, 0, len(fields))
+		for name := range fields {
+			found = append(found, name)
+		}
+		sort.Strings(found)
+		message := fmt.Sprintf("secret payload has no key/value fields: expected %q and %q, found %q",
+			c.SecretKeyField, c.SecretValueField, found)
+		return RawSecret{}, &APIError{Message: message}
+	}
+
+	var secret RawSecret
+	if err := json.Unmarshal(fields[keyField], &secret.Key); err != nil {
+		message := fmt.Sprintf("secret field %q is not a string, found %s", keyField, jsonKind(string(fields[keyField])))
+		return RawSecret{}, &APIError{Err: err, Message: message}
+	}
+	if err := json.Unmarshal(fields[valueField], &secret.Value); err != nil {
+		secret.Value = string(fields[valueField])
+	}
+	// A missing or malformed timestamp is left out rather than failing the
+	// secret, as it is informational.
+	var updatedAt time.Time
+	if raw, ok := fields[updatedAtField]; ok && json.Unmarshal(raw, &updatedAt) == nil {
+		secret.UpdatedAt = &updatedAt
+	}
+	return secret, nil
+}
+
+// maxErrorDataLength caps the decrypted data included in an error, which
+// may hold secret values.
+const maxErrorDataLength = 128
 
-	response, err := c.performRequest("/secrets", "GET", headers{}, params, httpRequestBody{})
+// truncateData shortens data to maxErrorDataLength bytes, without splitting
+// a UTF-8 sequence, and notes how much was dropped.
+func truncateData(data string) string {
+	if len(data) <= maxErrorDataLength {
+		return data
+	}
+	end := maxErrorDataLength
+	for end > 0 && !utf8.RuneStart(data[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", data[:end], len(data)-end)
+}
+
+// jsonKind names the type of a JSON document for error messages.
+func jsonKind(data string) string {
+	trimmed := strings.TrimSpace(data)
+	if trimmed == "" {
+		return "an empty payload"
+	}
+	if !json.Valid([]byte(trimmed)) {
+		return "invalid JSON"
+	}
+	switch trimmed[0] {
+	case '{':
+		return "an object"
+	case '[':
+		return "an array"
+	case '"':
+		return "a string"
+	case 't', 'f':
+		return "a boolean"
+	case 'n':
+		return "null"
+	default:
+		return "a number"
+	}
+}
+
+// lookupField returns the first of the given field names present in fields.
+func lookupField(fields map[string]json.RawMessage, names ...string) string {
+	for _, name := range names {
+		if _, ok := fields[name]; ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// decryptSecret decrypts a single secret. Decryption is deterministic, so a
+// failure is not retried on the same ciphertext. Payloads that are not well
+// formed are rejected before reaching the decryption library, which is not
+// hardened against them, and a panic of the library is returned as an error.
+func decryptSecret(secret, passphrase string) (decrypted string, err error) {
+	if _, _, err := splitPayload(secret); err != nil {
+		return "", err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			decrypted, err = "", fmt.Errorf("decryption failed: %v", r)
+		}
+	}()
+	return aesdecrypt.Run(secret, passphrase)
+}
+
+func (c *OnboardbaseClient) GetSecret(ctx context.Context, request SecretRequest) (*SecretResponse, error) {
+	secrets, err := c.fetchSecretEntries(ctx, request.Headers, request.buildQueryParams(c.TokenScopedProject), request.Project, request.Environment)
 	if err != nil {
 		return nil, err
 	}
 
-	var data secretResponseBody
-	if err := json.Unmarshal(response.Body, &data); err != nil {
-		return nil, &APIError{Err: err, Message: "unable to unmarshal secret payload", Data: string(response.Body)}
+	secret, found := secrets[request.Name]
+	if !found || (secret.Value == "" && !c.AllowEmptyValues) {
+		return nil, &APIError{Err: ErrSecretNotFound, Message: fmt.Sprintf("secret %s for project '%s' and environment '%s' not found", request.Name, request.Project, request.Environment)}
+	}
+
+	return &SecretResponse{Name: request.Name, Value: secret.Value, UpdatedAt: secret.UpdatedAt}, nil
+}
+
+// GetSecretsByNames fetches the secrets of an environment once and returns
+// those named in names, keyed by name, or all of them when names is nil. As
+// with GetSecret, names the environment lacks are left out, as are empty
+// values unless AllowEmptyValues is set.
+func (c *OnboardbaseClient) GetSecretsByNames(ctx context.Context, request SecretsRequest, names []string) (map[string]*SecretResponse, error) {
+	if request.AllEnvironments {
+		return nil, &APIError{Message: "cannot get secrets by name across all environments"}
+	}
+	if names != nil && len(names) == 0 {
+		return map[string]*SecretResponse{}, nil
+	}
+	secrets, err := c.fetchSecretEntries(ctx, headers{}, request.buildQueryParams(c.TokenScopedProject), request.Project, request.Environment)
+	if err != nil {
+		return nil, err
 	}
 
-	secrets, _ := c.getSecretsFromPayload(data.Data)
-	secret := secrets[request.Name]
+	if names == nil {
+		names = make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+	}
+	found := make(map[string]*SecretResponse, len(names))
+	for _, name := range names {
+		secret, ok := secrets[name]
+		if !ok || (secret.Value == "" && !c.AllowEmptyValues) {
+			continue
+		}
+		found[name] = &SecretResponse{Name: name, Value: secret.Value, UpdatedAt: secret.UpdatedAt}
+	}
+	return found, nil
+}
 
-	if secret == "" {
-		return nil, &APIError{Message: fmt.Sprintf("secret %s for project '%s' and environment '%s' not found", request.Name, request.Project, request.Environment)}
+// fetchSecretEntries fetches, decrypts and parses the secrets of an
+// environment.
+func (c *OnboardbaseClient) fetchSecretEntries(ctx context.Context, headers headers, params queryParams, project, environment string) (map[string]RawSecret, error) {
+	data, _, err := c.secretsFetcher().fetchSecrets(ctx, c, headers, params)
+	if err != nil {
+		return nil, err
 	}
+	if err := c.fetchPayload(ctx, &data.Data); err != nil {
+		return nil, err
+	}
+	entries, err := c.getSecretEntries(data.Data)
+	if err != nil {
+		return nil, err
+	}
+	c.syncTracker.record(project, environment, c.Clock.Now())
+	return entries, nil
+}
 
-	return &SecretResponse{Name: request.Name, Value: secrets[request.Name]}, nil
+// GetSecretsModifiedSince fetches the secrets changed after since. API
+// versions without the modifiedSince filter ignore it and return every
+// secret, so callers must treat the result as a superset of the changes.
+func (c *OnboardbaseClient) GetSecretsModifiedSince(ctx context.Context, request SecretsRequest, since time.Time) (*SecretsResponse, error) {
+	request.ModifiedSince = since
+	return c.GetSecrets(ctx, request)
+}
+
+func (c *OnboardbaseClient) GetSecrets(ctx context.Context, request SecretsRequest) (*SecretsResponse, error) {
+	var response *SecretsResponse
+	var err error
+	if request.AllEnvironments {
+		response, err = c.getProjectSecrets(ctx, request)
+	} else {
+		response, err = c.getSecrets(ctx, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := response.render(request.Format); err != nil {
+		return nil, err
+	}
+	return response, nil
 }
 
-func (c *OnboardbaseClient) GetSecrets(request SecretsRequest) (*SecretsResponse, error) {
+func (c *OnboardbaseClient) getSecrets(ctx context.Context, request SecretsRequest) (*SecretsResponse, error) {
 	headers := headers{}
 
-	params := request.buildQueryParams()
-	response, apiErr := c.performRequest("/secrets", "GET", headers, params, httpRequestBody{})
-	if apiErr != nil {
-		return nil, apiErr
+	params := request.buildQueryParams(c.TokenScopedProject)
+	data, body, err := c.secretsFetcher().fetchSecrets(ctx, c, headers, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.fetchPayload(ctx, &data.Data); err != nil {
+		return nil, err
+	}
+
+	// The sync is only recorded once the payload decrypted and parsed.
+	if c.RawPayloads {
+		raw, err := c.getDecryptedRaw(data.Data)
+		if err != nil {
+			return nil, err
+		}
+		c.syncTracker.record(request.Project, request.Environment, c.Clock.Now())
+		return &SecretsResponse{RawPayloads: raw, Body: body}, nil
+	}
+
+	secrets, err := c.getSecretsFromPayload(data.Data)
+	if err != nil {
+		return nil, err
+	}
+	c.syncTracker.record(request.Project, request.Environment, c.Clock.Now())
+	return &SecretsResponse{Secrets: secrets, Body: body}, nil
+}
+
+// environmentNotFound turns a 404 from the secrets endpoint into an error
+// naming the project and environment that were requested.
+func environmentNotFound(err error, project, environment string) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		return err
+	}
+	notFound := *apiErr
+	notFound.Message = fmt.Sprintf("no secrets found for project '%s' and environment '%s'; check that both exist and that the API key can access them", project, environment)
+	notFound.Err = ErrEnvironmentNotFound
+	if apiErr.Err == nil && apiErr.Message != "" {
+		notFound.Err = fmt.Errorf("%w: %s", ErrEnvironmentNotFound, apiErr.Message)
+	}
+	return &notFound
+}
+
+// getProjectSecrets fetches the secrets of all environments of a project. The
+// API answers an environment-less query with one entry per environment; an
+// object-shaped answer means the query is not supported by this API.
+func (c *OnboardbaseClient) getProjectSecrets(ctx context.Context, request SecretsRequest) (*SecretsResponse, error) {
+	if request.Environment != "" {
+		return nil, &APIError{Message: fmt.Sprintf("environment '%s' must be empty when fetching all environments", request.Environment)}
+	}
+
+	params := request.buildQueryParams(c.TokenScopedProject)
+	response, err := c.performRequest(ctx, "/secrets", "GET", headers{}, params, httpRequestBody{})
+	if err != nil {
+		return nil, err
+	}
+
+	var data projectSecretsResponseBody
+	if err := json.Unmarshal(response.Body, &data); err != nil {
+		return nil, &APIError{Err: err, Message: fmt.Sprintf("the API does not support fetching secrets across environments of project '%s'", request.Project)}
+	}
+
+	secrets := Secrets{}
+	var rawPayloads []string
+	for _, environment := range data.Data {
+		if environment.Environment.Title == "" {
+			return nil, &APIError{Message: fmt.Sprintf("the API returned secrets without an environment for project '%s'", request.Project)}
+		}
+		if c.RawPayloads {
+			raw, err := c.getDecryptedRaw(environment)
+			if err != nil {
+				return nil, err
+			}
+			rawPayloads = append(rawPayloads, raw...)
+			continue
+		}
+		kv, err := c.getSecretsFromPayload(environment)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range kv {
+			secrets[environment.Environment.Title+EnvironmentKeySeparator+key] = value
+		}
 	}
+	if c.RawPayloads {
+		return &SecretsResponse{RawPayloads: rawPayloads, Body: response.Body}, nil
+	}
+	return &SecretsResponse{Secrets: secrets, Body: response.Body}, nil
+}
 
+// ListProjects returns the projects the API key has access to.
+func (c *OnboardbaseClient) ListProjects(ctx context.Context) ([]Project, error) {
+	response, err := c.performRequest(ctx, "/projects", "GET", headers{}, queryParams{}, httpRequestBody{})
+	if err != nil {
+		return nil, err
+	}
 
-	var data secretResponseBody
+	var data projectsResponseBody
 	if err := json.Unmarshal(response.Body, &data); err != nil {
-		return nil, &APIError{Err: err, Message: "unable to unmarshal secret payload", Data: string(response.Body)}
+		return nil, &APIError{Err: err, Message: "unable to unmarshal projects payload", Data: string(response.Body)}
+	}
+
+	projects := make([]Project, 0, len(data.Data))
+	for _, p := range data.Data {
+		project := Project{Title: p.Title}
+		for _, environment := range p.Environments {
+			project.Environments = append(project.Environments, environment.Title)
+		}
+		projects = append(projects, project)
+	}
+	return projects, nil
+}
+
+// UpdateSecrets creates or updates the given secrets. Every request carries an
+// Idempotency-Key header derived from the request content, so that a retried
+// write is recognized by the server instead of being applied twice.
+func (c *OnboardbaseClient) UpdateSecrets(ctx context.Context, request UpdateSecretsRequest) error {
+	plain, err := json.Marshal(request)
+	if err != nil {
+		return &APIError{Err: err, Message: "unable to marshal update request"}
+	}
+	body, err := c.marshalUpdate(request)
+	if err != nil {
+		return err
+	}
+
+	// Encryption is salted, so the key is derived from the plain request.
+	headers := headers{headerIdempotencyKey: idempotencyKey(plain)}
+	if _, err := c.performCompressedRequest(ctx, "/secrets", "POST", headers, queryParams{}, body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// idempotencyKey returns a stable key for a write request body.
+func idempotencyKey(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteSecrets deletes the requested secrets, issuing a single delete call per
+// project and environment. Outcomes are reported per secret. If ctx is cancelled
+// between batches, the remaining batches are skipped and the response lists the
+// secrets that were already deleted alongside the context error.
+func (c *OnboardbaseClient) DeleteSecrets(ctx context.Context, requests []SecretRequest) (*DeleteSecretsResponse, error) {
+	response := &DeleteSecretsResponse{}
+
+	for _, batch := range groupSecretRequests(requests) {
+		if err := ctx.Err(); err != nil {
+			return response, &APIError{Err: err, Message: "bulk delete aborted"}
+		}
+
+		body, err := json.Marshal(batch)
+		if err != nil {
+			return response, &APIError{Err: err, Message: "unable to marshal delete request"}
+		}
+
+		_, err = c.performRequest(ctx, "/secrets", "DELETE", headers{}, queryParams{}, body)
+		for _, name := range batch.Secrets {
+			request := SecretRequest{Project: batch.Project, Environment: batch.Environment, Name: name}
+			response.Results = append(response.Results, DeleteSecretResult{Request: request, Err: err})
+		}
+		if err != nil && ctx.Err() != nil {
+			return response, &APIError{Err: ctx.Err(), Message: "bulk delete aborted"}
+		}
 	}
 
-	secrets, _ := c.getSecretsFromPayload(data.Data)
-	return &SecretsResponse{ Secrets: secrets, Body: response.Body}, nil
+	return response, nil
 }
 
-func (r *SecretsRequest) buildQueryParams() queryParams {
+// Deleted returns the secrets that were successfully deleted.
+func (r *DeleteSecretsResponse) Deleted() []SecretRequest {
+	deleted := make([]SecretRequest, 0, len(r.Results))
+	for _, result := range r.Results {
+		if result.Err == nil {
+			deleted = append(deleted, result.Request)
+		}
+	}
+	return deleted
+}
+
+// Err returns the first per-secret error, if any.
+func (r *DeleteSecretsResponse) Err() error {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+// groupSecretRequests batches requests by project and environment, keeping the
+// order in which each batch was first seen.
+func groupSecretRequests(requests []SecretRequest) []DeleteSecretsRequest {
+	batches := []DeleteSecretsRequest{}
+	index := map[[2]string]int{}
+	for _, request := range requests {
+		key := [2]string{request.Project, request.Environment}
+		i, ok := index[key]
+		if !ok {
+			i = len(batches)
+			index[key] = i
+			batches = append(batches, DeleteSecretsRequest{Project: request.Project, Environment: request.Environment})
+		}
+		batches[i].Secrets = append(batches[i].Secrets, request.Name)
+	}
+	return batches
+}
+
+// buildQueryParams leaves the project out with scopedProject, for the API to
+// use the project the API key is scoped to.
+func (r *SecretsRequest) buildQueryParams(scopedProject bool) queryParams {
 	params := queryParams{}
 
-	if r.Project != "" {
+	if r.Project != "" && !scopedProject {
 		params["project"] = r.Project
 	}
 
-
 	if r.Environment != "" {
 		params["environment"] = r.Environment
 	}
 
+	if !r.ModifiedSince.IsZero() {
+		params["modifiedSince"] = r.ModifiedSince.UTC().Format(time.RFC3339)
+	}
+
 	return params
 }
 
-
-func (r *SecretRequest) buildQueryParams() queryParams {
+// buildQueryParams leaves the project out with scopedProject, like
+// SecretsRequest.buildQueryParams.
+func (r *SecretRequest) buildQueryParams(scopedProject bool) queryParams {
 	params := queryParams{}
 
-	if r.Project != "" {
+	if r.Project != "" && !scopedProject {
 		params["project"] = r.Project
 	}
 
-
 	if r.Environment != "" {
 		params["environment"] = r.Environment
 	}
@@ -261,8 +941,41 @@ func (r *SecretRequest) buildQueryParams() queryParams {
 	return params
 }
 
-func (c *OnboardbaseClient) performRequest(path, method string, headers headers, params queryParams, body httpRequestBody) (*apiResponse, error) {
-	urlStr := c.BaseURL().String() + path
+func (c *OnboardbaseClient) performRequest(ctx context.Context, path, method string, headers headers, params queryParams, body httpRequestBody) (*apiResponse, error) {
+	if err := c.allowRequest(method, path); err != nil {
+		return nil, err
+	}
+	response, err := c.performRequestWithFailover(ctx, path, method, headers, params, body)
+	for retry := 1; retry <= c.MaxRetries && err != nil && c.shouldRetry(ctx, method, headers, err); retry++ {
+		if c.sleep(ctx, c.backoff().Delay(retry)) != nil {
+			break
+		}
+		response, err = c.performRequestWithFailover(ctx, path, method, headers, params, body)
+	}
+	c.recordRequest(method, err, ctx.Err())
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Operation = method + " " + path
+	}
+	return response, err
+}
+
+func (c *OnboardbaseClient) performRequestWithFailover(ctx context.Context, path, method string, headers headers, params queryParams, body httpRequestBody) (*apiResponse, error) {
+	response, err := c.performHedgedRequestTo(ctx, c.urlFor(method), path, method, headers, params, body)
+	c.mu.RLock()
+	failoverURLs := c.failoverURLs
+	c.mu.RUnlock()
+	for _, baseURL := range failoverURLs {
+		if err == nil || !shouldFailover(ctx, method, headers, err) {
+			break
+		}
+		response, err = c.performHedgedRequestTo(ctx, baseURL, path, method, headers, params, body)
+	}
+	return response, err
+}
+
+func (c *OnboardbaseClient) performRequestTo(ctx context.Context, baseURL *url.URL, path, method string, headers headers, params queryParams, body httpRequestBody) (*apiResponse, error) {
+	urlStr := baseURL.String() + path
 	reqURL, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, &APIError{Err: err, Message: fmt.Sprintf("invalid API URL: %s", urlStr)}
@@ -275,12 +988,14 @@ func (c *OnboardbaseClient) performRequest(path, method string, headers headers,
 		bodyReader = http.NoBody
 	}
 
-	req, err := http.NewRequest(method, reqURL.String(), bodyReader)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), bodyReader)
 	if err != nil {
 		return nil, &APIError{Err: err, Message: "unable to form HTTP request"}
 	}
 
-	if method == "POST" && req.Header.Get("content-type") == "" {
+	if (method == "POST" || method == "DELETE") && req.Header.Get("content-type") == "" {
 		req.Header.Set("content-type", "application/json")
 	}
 
@@ -288,11 +1003,19 @@ func (c *OnboardbaseClient) performRequest(path, method string, headers headers,
 		req.Header.Set("accept", "application/json")
 	}
 	req.Header.Set("user-agent", c.UserAgent)
-	req.Header.Set("api_key", c.OnboardbaseAPIKey)
+	if c.AcceptLanguage != "" {
+		req.Header.Set("accept-language", c.AcceptLanguage)
+	}
 
-	for key, value := range headers {
-		req.Header.Set(key, value)
+	// Per-request headers take precedence over ExtraHeaders; neither may
+	// replace the api_key header.
+	for _, extra := range []map[string]string{c.ExtraHeaders, headers} {
+		for key, value := range extra {
+			req.Header.Set(key, value)
+		}
 	}
+	req.Header.Del(HeaderAPIKey)
+	req.Header.Set(HeaderAPIKey, c.apiKey())
 
 	query := req.URL.Query()
 	for key, value := range params {
@@ -300,6 +1023,23 @@ func (c *OnboardbaseClient) performRequest(path, method string, headers headers,
 	}
 	req.URL.RawQuery = query.Encode()
 
+	// The body is signed from the same buffer it is sent from, and the query
+	// as it is sent.
+	if len(c.SigningKey) > 0 {
+		if err := c.checkSigningBodySize(body); err != nil {
+			return nil, err
+		}
+		timestamp := strconv.FormatInt(c.Clock.Now().Unix(), 10)
+		req.Header.Set(SigningTimestampHeader, timestamp)
+		req.Header.Set(c.signingHeader(), signRequest(c.SigningKey, method, req.URL.EscapedPath(), req.URL.RawQuery, timestamp, body))
+	}
+
+	c.traceRequest(req, body)
+
+	if err := c.rateLimiter.wait(ctx, c.Clock); err != nil {
+		return nil, &APIError{Err: err, Message: "aborted while waiting for rate limit"}
+	}
+
 	r, err := c.httpClient.Do(req)
 
 	if err != nil {
@@ -307,7 +1047,9 @@ func (c *OnboardbaseClient) performRequest(path, method string, headers headers,
 	}
 	defer r.Body.Close()
 
-	bodyResponse, err := io.ReadAll(r.Body)
+	c.rateLimiter.observe(reqURL.Host, r.Header, c.Clock.Now())
+
+	bodyResponse, err := c.readBody(r.Body, cancel)
 	if err != nil {
 		return &apiResponse{HTTPResponse: r, Body: nil}, &APIError{Err: err, Message: "unable to read entire response body"}
 	}
@@ -316,23 +1058,64 @@ func (c *OnboardbaseClient) performRequest(path, method string, headers headers,
 	success := isSuccess(r.StatusCode)
 
 	if !success {
+		requestID := r.Header.Get(headerRequestID)
 		if contentType := r.Header.Get("content-type"); strings.HasPrefix(contentType, "application/json") {
 			var errResponse apiErrorResponse
 			err := json.Unmarshal(bodyResponse, &errResponse)
 			if err != nil {
-				return response, &APIError{Err: err, Message: "unable to unmarshal error JSON payload"}
+				return response, &APIError{Err: err, Message: "unable to unmarshal error JSON payload", StatusCode: r.StatusCode, RequestID: requestID}
 			}
-			return response, &APIError{Err: nil, Message: strings.Join(errResponse.Messages, "\n")}
+			return response, &APIError{Err: nil, Message: strings.Join(errResponse.Messages, "\n"), StatusCode: r.StatusCode, RequestID: requestID}
 		}
-		return nil, &APIError{Err: fmt.Errorf("%d status code; %d bytes", r.StatusCode, len(bodyResponse)), Message: "unable to load response"}
+		return nil, &APIError{Err: fmt.Errorf("%d status code; %d bytes", r.StatusCode, len(bodyResponse)), Message: "unable to load response", StatusCode: r.StatusCode, RequestID: requestID}
 	}
 
 	if success && err != nil {
 		return nil, &APIError{Err: err, Message: "unable to load data from successful response"}
 	}
+	if err := c.checkSuccessCode(method, path, r.StatusCode); err != nil {
+		return response, &APIError{Err: err, Message: "unexpected status code", StatusCode: r.StatusCode, RequestID: r.Header.Get(headerRequestID)}
+	}
 	return response, nil
 }
 
+// DebugAPIErrors makes APIError include its Data field when marshalled to
+// JSON. Data may hold raw response bodies or decrypted secret payloads, so
+// it is redacted by default.
+var DebugAPIErrors = false
+
+// MarshalJSON encodes the error for structured logs and events. Data is
+// redacted unless DebugAPIErrors is set.
+func (e *APIError) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Message    string `json:"message"`
+		Error      string `json:"error,omitempty"`
+		StatusCode int    `json:"statusCode,omitempty"`
+		RequestID  string `json:"requestID,omitempty"`
+		Operation  string `json:"operation,omitempty"`
+		Data       string `json:"data,omitempty"`
+	}{
+		Message:    e.Message,
+		StatusCode: e.StatusCode,
+		RequestID:  e.RequestID,
+		Operation:  e.Operation,
+	}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	if e.Data != "" {
+		out.Data = redacted
+		if DebugAPIErrors {
+			out.Data = e.Data
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
 func isSuccess(statusCode int) bool {
 	return (statusCode >= 200 && statusCode <= 299) || (statusCode >= 300 && statusCode <= 399)
 }
@@ -0,0 +1,2165 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *OnboardbaseClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := NewOnboardbaseClient("api-key", "passcode")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestDeleteSecretsGroupsByProjectAndEnvironment(t *testing.T) {
+	var batches []DeleteSecretsRequest
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/secrets" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var batch DeleteSecretsRequest
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("unable to decode body: %v", err)
+		}
+		batches = append(batches, batch)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	response, err := c.DeleteSecrets(context.Background(), []SecretRequest{
+		{Project: "app", Environment: "dev", Name: "A"},
+		{Project: "app", Environment: "prod", Name: "B"},
+		{Project: "app", Environment: "dev", Name: "C"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 delete calls, got %d", len(batches))
+	}
+	if got := batches[0].Secrets; len(got) != 2 || got[0] != "A" || got[1] != "C" {
+		t.Errorf("unexpected first batch: %v", got)
+	}
+	if got := len(response.Deleted()); got != 3 {
+		t.Errorf("expected 3 deleted secrets, got %d", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDeleteSecretsAbortsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	})
+	transport := c.httpClient.Transport
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		defer cancel()
+		return transport.RoundTrip(req)
+	})
+
+	response, err := c.DeleteSecrets(ctx, []SecretRequest{
+		{Project: "app", Environment: "dev", Name: "A"},
+		{Project: "app", Environment: "prod", Name: "B"},
+	})
+	if err == nil {
+		t.Fatalf("expected cancellation error")
+	}
+	if calls != 1 {
+		t.Errorf("expected a single delete call, got %d", calls)
+	}
+	if deleted := response.Deleted(); len(deleted) != 1 || deleted[0].Name != "A" {
+		t.Errorf("unexpected deleted secrets: %v", deleted)
+	}
+}
+
+func TestRateLimiterSlowsDownAsBudgetShrinks(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter()
+
+	limiter.observe("test", http.Header{}, now)
+	if d := limiter.delay(now); d != 0 {
+		t.Errorf("expected no delay without headers, got %s", d)
+	}
+
+	header := http.Header{}
+	header.Set(headerRateLimitRemaining, "100")
+	header.Set(headerRateLimitReset, "60")
+	limiter.observe("test", header, now)
+	if d := limiter.delay(now); d != 0 {
+		t.Errorf("expected no delay with a large budget, got %s", d)
+	}
+
+	header.Set(headerRateLimitRemaining, "5")
+	limiter.observe("test", header, now)
+	if d := limiter.delay(now); d != 10*time.Second {
+		t.Errorf("expected 10s delay, got %s", d)
+	}
+
+	header.Set(headerRateLimitRemaining, "0")
+	header.Set(headerRateLimitReset, "1700000030")
+	limiter.observe("test", header, now)
+	if d := limiter.delay(now); d != 30*time.Second {
+		t.Errorf("expected to wait until reset, got %s", d)
+	}
+}
+
+func TestUpdateSecretsSendsStableIdempotencyKey(t *testing.T) {
+	var keys []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/secrets" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		keys = append(keys, r.Header.Get(headerIdempotencyKey))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := UpdateSecretsRequest{Project: "app", Environment: "dev", Secrets: RawSecrets{{Key: "A", Value: "1"}}}
+	for i := 0; i < 2; i++ {
+		if err := c.UpdateSecrets(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	request.Secrets[0].Value = "2"
+	if err := c.UpdateSecrets(context.Background(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected identical idempotency keys for identical pushes, got %v", keys)
+	}
+	if keys[0] == keys[2] {
+		t.Errorf("expected a different idempotency key for a different value")
+	}
+}
+
+// fakeClock is a Clock whose time only moves when advanced by the test.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.waiters = append(f.waiters, fakeClockWaiter{deadline: f.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward, firing every waiter whose deadline passed.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if !w.deadline.After(f.now) {
+			w.ch <- f.now
+			continue
+		}
+		pending = append(pending, w)
+	}
+	f.waiters = pending
+}
+
+// waiting reports how many callers are blocked on After.
+func (f *fakeClock) waiting() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+func TestRateLimiterWaitUsesClock(t *testing.T) {
+	clock := newFakeClock(time.Unix(1700000000, 0))
+	limiter := newRateLimiter()
+	header := http.Header{}
+	header.Set(headerRateLimitRemaining, "1")
+	header.Set(headerRateLimitReset, "10")
+	limiter.observe("test", header, clock.Now())
+
+	done := make(chan error)
+	go func() {
+		done <- limiter.wait(context.Background(), clock)
+	}()
+
+	for clock.waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(4 * time.Second)
+	select {
+	case <-done:
+		t.Fatalf("wait returned before the delay elapsed")
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	low := func() float64 { return 0 }
+	testCases := []struct {
+		label    string
+		backoff  Backoff
+		expected []time.Duration
+	}{
+		{label: "constant", backoff: ConstantBackoff{Interval: time.Second}, expected: []time.Duration{time.Second, time.Second, time.Second}},
+		{label: "linear", backoff: LinearBackoff{Base: time.Second, Max: 5 * time.Second}, expected: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}},
+		{label: "exponential without jitter", backoff: ExponentialBackoff{Base: time.Second, Max: 6 * time.Second, random: low}, expected: []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{label: "exponential with full jitter", backoff: ExponentialBackoff{Base: time.Second, Max: 6 * time.Second, random: func() float64 { return 1 }}, expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 6 * time.Second}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			for i, expected := range tc.expected {
+				if got := tc.backoff.Delay(i + 1); got != expected {
+					t.Errorf("unexpected delay before retry %d: expected %s, got %s", i+1, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, r.Method)
+		if len(attempts) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	clock := newFakeClock(time.Unix(1700000000, 0))
+	c.Clock = clock
+	c.MaxRetries = 3
+	c.Backoff = ConstantBackoff{Interval: time.Second}
+
+	done := make(chan error)
+	go func() {
+		_, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil)
+		done <- err
+	}()
+	for retries := 0; retries < 2; retries++ {
+		for clock.waiting() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clock.Advance(time.Second)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected the request to succeed on its third attempt, got %v", err)
+	}
+
+	mu.Lock()
+	attempts = nil
+	mu.Unlock()
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodPost, headers{}, nil, []byte("{}")); err == nil {
+		t.Fatalf("expected the write to fail")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{http.MethodPost}; !reflect.DeepEqual(attempts, want) {
+		t.Errorf("expected a write without idempotency key not to be retried, got %v", attempts)
+	}
+}
+
+func TestRetryableStatusCodes(t *testing.T) {
+	testCases := []struct {
+		label     string
+		codes     []int
+		status    int
+		retryable bool
+	}{
+		{label: "5xx by default", status: http.StatusServiceUnavailable, retryable: true},
+		{label: "429 by default", status: http.StatusTooManyRequests, retryable: true},
+		{label: "4xx not by default", status: http.StatusRequestTimeout},
+		{label: "listed code", codes: []int{http.StatusRequestTimeout, http.StatusTooEarly}, status: http.StatusTooEarly, retryable: true},
+		{label: "5xx still retried with listed codes", codes: []int{http.StatusRequestTimeout}, status: http.StatusServiceUnavailable, retryable: true},
+		{label: "429 still retried with listed codes", codes: []int{http.StatusRequestTimeout}, status: http.StatusTooManyRequests, retryable: true},
+		{label: "4xx not listed", codes: []int{http.StatusRequestTimeout}, status: http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			attempts := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(tc.status)
+			})
+			c.MaxRetries = 1
+			c.Backoff = ConstantBackoff{}
+			c.RetryableStatusCodes = tc.codes
+			if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil); err == nil {
+				t.Fatalf("expected the request to fail")
+			}
+			if retried := attempts > 1; retried != tc.retryable {
+				t.Errorf("expected retried to be %v, got %d attempts", tc.retryable, attempts)
+			}
+		})
+	}
+}
+
+// encryptSecret produces a CryptoJS compatible AES payload for a key/value pair.
+func encryptSecret(t *testing.T, passphrase, key, value string) string {
+	t.Helper()
+	encrypted, err := EncryptSecret(passphrase, key, value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return encrypted
+}
+
+func TestGetSecretsAcrossEnvironments(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("environment") {
+			t.Errorf("unexpected environment query parameter")
+		}
+		body := projectSecretsResponseBody{Data: []secretResponseBodyData{
+			{Environment: secretResponseBodyObject{Title: "dev"}, Secrets: []string{encryptSecret(t, "passcode", "DB", "dev-db")}},
+			{Environment: secretResponseBodyObject{Title: "prod"}, Secrets: []string{encryptSecret(t, "passcode", "DB", "prod-db")}},
+		}}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+
+	response, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", AllEnvironments: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Secrets{"dev/DB": "dev-db", "prod/DB": "prod-db"}
+	if len(response.Secrets) != len(want) || response.Secrets["dev/DB"] != want["dev/DB"] || response.Secrets["prod/DB"] != want["prod/DB"] {
+		t.Errorf("unexpected secrets: %v", response.Secrets)
+	}
+}
+
+func TestGetSecretsAcrossEnvironmentsUnsupported(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(secretResponseBody{})
+	})
+
+	if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", AllEnvironments: true}); err == nil {
+		t.Fatalf("expected an error for an unsupported environment-less query")
+	}
+}
+
+func TestDecryptSecret(t *testing.T) {
+	secret := encryptSecret(t, "passcode", "A", "1")
+	if _, err := decryptSecret(secret, "passcode"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := decryptSecret("not-base64!", "passcode"); err == nil {
+		t.Errorf("expected an error for a malformed payload")
+	}
+}
+
+func TestTokenScopedProject(t *testing.T) {
+	var query url.Values
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		_ = json.NewEncoder(w).Encode(secretResponseBody{})
+	})
+	c.TokenScopedProject = true
+
+	if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query.Has("project") || query.Get("environment") != "dev" {
+		t.Errorf("expected only the environment in the query, got %v", query)
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	good := encryptSecret(t, "passcode", "A", "1")
+	bad := []string{encryptSecret(t, "other", "B", "2"), encryptSecret(t, "other", "C", "3")}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body := secretResponseBody{}
+		body.Data.Project.Title = r.URL.Query().Get("project")
+		body.Data.Environment.Title = r.URL.Query().Get("environment")
+		body.Data.Secrets = append([]string{good}, bad...)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+
+	_, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"})
+	if err == nil || !strings.Contains(err.Error(), secretID(bad[0])) {
+		t.Fatalf("expected an error naming %s, got %v", secretID(bad[0]), err)
+	}
+	if failures := testutil.ToFloat64(decryptFailures.WithLabelValues(c.BaseURL().Host, "app", "dev")); failures != float64(len(bad)) {
+		t.Errorf("expected %d failures, got %v", len(bad), failures)
+	}
+}
+
+func TestFallbackPasscodes(t *testing.T) {
+	rotated := encryptSecret(t, "passcode", "A", "1")
+	stale := encryptSecret(t, "previous", "B", "2")
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body := secretResponseBody{}
+		body.Data.Secrets = []string{rotated, stale}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+
+	if _, err := c.GetSecrets(context.Background(), request); err == nil {
+		t.Fatalf("expected an error without fallback passcodes")
+	}
+
+	c.FallbackPasscodes = []string{"unrelated", "previous"}
+	response, err := c.GetSecrets(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (Secrets{"A": "1", "B": "2"}); !reflect.DeepEqual(response.Secrets, expected) {
+		t.Errorf("unexpected secrets: expected %v, got %v", expected, response.Secrets)
+	}
+	if uses := testutil.ToFloat64(fallbackPasscodeDecrypts.WithLabelValues(c.BaseURL().Host, "1")); uses != 1 {
+		t.Errorf("expected one use of the second fallback passcode, got %v", uses)
+	}
+}
+
+func TestParseRawSecretFieldMapping(t *testing.T) {
+	updatedAt := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		label       string
+		keyField    string
+		valueField  string
+		payload     string
+		expected    RawSecret
+		expectError string
+	}{
+		{label: "default fields", keyField: "key", valueField: "value", payload: `{"key":"A","value":"1"}`, expected: RawSecret{Key: "A", Value: "1"}},
+		{label: "mapped fields", keyField: "name", valueField: "secret", payload: `{"name":"A","secret":"1"}`, expected: RawSecret{Key: "A", Value: "1"}},
+		{label: "mapped falls back to defaults", keyField: "name", valueField: "secret", payload: `{"key":"A","value":"1"}`, expected: RawSecret{Key: "A", Value: "1"}},
+		{label: "non-string value", keyField: "key", valueField: "value", payload: `{"key":"A","value":{"b":2}}`, expected: RawSecret{Key: "A", Value: `{"b":2}`}},
+		{label: "updatedAt", keyField: "key", valueField: "value", payload: `{"key":"A","value":"1","updatedAt":"2023-03-01T12:00:00Z"}`, expected: RawSecret{Key: "A", Value: "1", UpdatedAt: &updatedAt}},
+		{label: "malformed updatedAt omitted", keyField: "key", valueField: "value", payload: `{"key":"A","value":"1","updatedAt":"yesterday"}`, expected: RawSecret{Key: "A", Value: "1"}},
+		{label: "missing fields", keyField: "name", valueField: "secret", payload: `{"title":"A","data":"1"}`, expectError: `expected "name" and "secret", found ["data" "title"]`},
+		{label: "not an object", keyField: "key", valueField: "value", payload: `["A","1"]`, expectError: `expected fields "key" and "value", found an array`},
+		{label: "invalid JSON", keyField: "key", valueField: "value", payload: `A=1`, expectError: "found invalid JSON"},
+		{label: "non-string key", keyField: "key", valueField: "value", payload: `{"key":1,"value":"1"}`, expectError: `secret field "key" is not a string, found a number`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := &OnboardbaseClient{SecretKeyField: tc.keyField, SecretValueField: tc.valueField}
+			out, err := c.parseRawSecret(tc.payload)
+			if tc.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+					t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(out, tc.expected) {
+				t.Errorf("unexpected secret: expected %#v, got %#v", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestParseRawSecretTruncatesData(t *testing.T) {
+	c := &OnboardbaseClient{SecretKeyField: "key", SecretValueField: "value"}
+	payload := "not json " + strings.Repeat("s3cr3t", 100)
+	_, err := c.parseRawSecret(payload)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	if !strings.HasPrefix(apiErr.Data, payload[:maxErrorDataLength]) || !strings.HasSuffix(apiErr.Data, fmt.Sprintf("(%d more bytes)", len(payload)-maxErrorDataLength)) {
+		t.Errorf("unexpected data: %q", apiErr.Data)
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	var leaked bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = true
+	}))
+	t.Cleanup(other.Close)
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cross-host":
+			http.Redirect(w, r, other.URL+"/secrets", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	})
+
+	_, err := c.performRequest(context.Background(), "/cross-host", http.MethodGet, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "refusing redirect") {
+		t.Errorf("expected cross-host redirect to be refused, got %v", err)
+	}
+	if leaked {
+		t.Errorf("request was forwarded to a different host")
+	}
+
+	_, err = c.performRequest(context.Background(), "/loop", http.MethodGet, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "stopped after") {
+		t.Errorf("expected redirect depth to be capped, got %v", err)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	var secondaryRequests []string
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api_key") != "api-key" {
+			t.Errorf("auth header not preserved on failover")
+		}
+		secondaryRequests = append(secondaryRequests, r.Method)
+	}))
+	t.Cleanup(secondary.Close)
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if err := c.SetFailoverURLs([]string{secondary.URL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Authenticate(context.Background()); err != nil {
+		t.Errorf("expected read to fail over, got %v", err)
+	}
+	if err := c.UpdateSecrets(context.Background(), UpdateSecretsRequest{Project: "app", Environment: "dev"}); err != nil {
+		t.Errorf("expected idempotent write to fail over, got %v", err)
+	}
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodPost, headers{}, nil, []byte("{}")); err == nil {
+		t.Errorf("expected write without idempotency key not to fail over")
+	}
+	if want := []string{http.MethodGet, http.MethodPost}; !reflect.DeepEqual(secondaryRequests, want) {
+		t.Errorf("unexpected failover requests: expected %v, got %v", want, secondaryRequests)
+	}
+}
+
+func TestHedging(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	primaryCancelled := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method)
+		first := len(requests) == 1
+		mu.Unlock()
+		if r.Method == http.MethodPost {
+			time.Sleep(50 * time.Millisecond)
+			return
+		}
+		if first {
+			select {
+			case <-r.Context().Done():
+				close(primaryCancelled)
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte(`{"hedge":true}`))
+	})
+	c.HedgeDelay = 10 * time.Millisecond
+
+	response, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(response.Body) != `{"hedge":true}` {
+		t.Errorf("expected the hedge response, got %s", response.Body)
+	}
+	select {
+	case <-primaryCancelled:
+	case <-time.After(time.Second):
+		t.Errorf("expected the slow request to be cancelled")
+	}
+
+	mu.Lock()
+	requests = nil
+	mu.Unlock()
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodPost, headers{}, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{http.MethodPost}; !reflect.DeepEqual(requests, want) {
+		t.Errorf("expected writes not to be hedged, got %v", requests)
+	}
+}
+
+func TestRequestSigning(t *testing.T) {
+	key := []byte("shared-secret")
+	var signatures []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(SigningTimestampHeader); got != "1700000000" {
+			t.Errorf("unexpected signing timestamp %q", got)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(r.Method + "\n" + r.URL.Path + "\n" + r.URL.RawQuery + "\n" + r.Header.Get(SigningTimestampHeader) + "\n"))
+		mac.Write(body)
+		if got, want := r.Header.Get("X-Gateway-Signature"), hex.EncodeToString(mac.Sum(nil)); got != want {
+			t.Errorf("unexpected signature for %s: expected %q, got %q", r.Method, want, got)
+		}
+		signatures = append(signatures, r.Header.Get("X-Gateway-Signature"))
+	})
+	c.SigningKey = key
+	c.SigningHeader = "X-Gateway-Signature"
+	c.Clock = newFakeClock(time.Unix(1700000000, 0))
+
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, queryParams{"project": "app"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, queryParams{"project": "other"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodPost, headers{}, nil, []byte(`{"key":"A"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(signatures) != 3 || signatures[0] == signatures[1] || signatures[0] == signatures[2] {
+		t.Errorf("expected distinct signatures for distinct queries and bodies, got %v", signatures)
+	}
+
+	c.SigningMaxBodySize = 8
+	_, err := c.performRequest(context.Background(), "/secrets", http.MethodPost, headers{}, nil, []byte(`{"key":"A"}`))
+	if err == nil || !strings.Contains(err.Error(), "request body of 11 bytes exceeds the 8 bytes that can be signed") {
+		t.Errorf("expected an error for a body too large to sign, got %v", err)
+	}
+	if len(signatures) != 3 {
+		t.Errorf("expected the request not to be sent, got %d requests", len(signatures))
+	}
+}
+
+func TestAPIErrorMarshalJSON(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerRequestID, "req-1")
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	_, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, nil, nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an APIError, got %v", err)
+	}
+	apiErr.Data = "s3cr3t"
+
+	out, err := json.Marshal(apiErr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"message":"unable to load response","error":"502 status code; 0 bytes","statusCode":502,"requestID":"req-1","operation":"GET /secrets","data":"[redacted]"}`
+	if string(out) != want {
+		t.Errorf("unexpected JSON: expected %s, got %s", want, out)
+	}
+
+	DebugAPIErrors = true
+	t.Cleanup(func() { DebugAPIErrors = false })
+	out, err = json.Marshal(apiErr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), `"data":"s3cr3t"`) {
+		t.Errorf("expected data in debug mode, got %s", out)
+	}
+}
+
+func TestGetSecretsModifiedSince(t *testing.T) {
+	var modifiedSince string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		modifiedSince = r.URL.Query().Get("modifiedSince")
+		_ = json.NewEncoder(w).Encode(secretResponseBody{})
+	})
+
+	since := time.Date(2023, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	if _, err := c.GetSecretsModifiedSince(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}, since); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "2023-05-01T10:00:00Z"; modifiedSince != want {
+		t.Errorf("unexpected modifiedSince: expected %q, got %q", want, modifiedSince)
+	}
+
+	if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if modifiedSince != "" {
+		t.Errorf("expected no modifiedSince filter, got %q", modifiedSince)
+	}
+}
+
+func TestDiffSecrets(t *testing.T) {
+	desired := map[string]string{"API": "key", "DB": "new-db", "NEW": "value"}
+	testCases := []struct {
+		label   string
+		current map[string]string
+		expect  SecretsDiff
+	}{
+		{
+			label:   "changes",
+			current: map[string]string{"API": "key", "DB": "old-db", "OLD": "value"},
+			expect:  SecretsDiff{Added: []string{"NEW"}, Changed: []string{"DB"}, Removed: []string{"OLD"}},
+		},
+		{
+			label:  "empty environment",
+			expect: SecretsDiff{Added: []string{"API", "DB", "NEW"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				body := secretResponseBody{}
+				for key, value := range tc.current {
+					body.Data.Secrets = append(body.Data.Secrets, encryptSecret(t, "passcode", key, value))
+				}
+				_ = json.NewEncoder(w).Encode(body)
+			})
+
+			diff, err := c.DiffSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}, desired)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*diff, tc.expect) {
+				t.Errorf("unexpected diff: expected %+v, got %+v", tc.expect, *diff)
+			}
+			if diff.Empty() {
+				t.Errorf("expected a non-empty diff")
+			}
+		})
+	}
+}
+
+func TestGetSecretsHonoursContext(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.GetSecrets(ctx, SecretsRequest{Project: "app", Environment: "dev"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline of the context to end the read, got %v", err)
+	}
+	if _, err := c.GetSecret(ctx, SecretRequest{Project: "app", Environment: "dev", Name: "A"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline of the context to end the read, got %v", err)
+	}
+}
+
+func TestGetSecretsByNames(t *testing.T) {
+	fetches := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		body := secretResponseBody{}
+		for key, value := range map[string]string{"API": "key", "DB": "db", "EMPTY": ""} {
+			body.Data.Secrets = append(body.Data.Secrets, encryptSecret(t, "passcode", key, value))
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+
+	secrets, err := c.GetSecretsByNames(context.Background(), request, []string{"API", "DB", "EMPTY", "MISSING"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches != 1 {
+		t.Errorf("expected a single fetch, got %d", fetches)
+	}
+	values := map[string]string{}
+	for name, secret := range secrets {
+		values[name] = secret.Value
+	}
+	if expected := map[string]string{"API": "key", "DB": "db"}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("unexpected secrets: expected %v, got %v", expected, values)
+	}
+
+	all, err := c.GetSecretsByNames(context.Background(), request, nil)
+	if err != nil || len(all) != 2 {
+		t.Errorf("expected all non-empty secrets, got %v, %v", all, err)
+	}
+
+	request.Environment = ""
+	request.AllEnvironments = true
+	if _, err := c.GetSecretsByNames(context.Background(), request, []string{"API"}); err == nil {
+		t.Errorf("expected an error across all environments")
+	}
+}
+
+func TestMarshalProperties(t *testing.T) {
+	secrets := Secrets{
+		"url":      "http://db:5432",
+		"a=b":      "1",
+		"my key":   " leading and trailing ",
+		"#comment": "!bang",
+		"path":     `C:\dir`,
+		"unicode":  "héllo € 😀",
+		"multi":    "a\nb\tc",
+	}
+	expected := `\#comment=\!bang
+a\=b=1
+multi=a\nb\tc
+my\ key=\ leading and trailing 
+path=C\:\\dir
+unicode=h\u00E9llo \u20AC \uD83D\uDE00
+url=http\://db\:5432
+`
+	if out := string(MarshalProperties(secrets)); out != expected {
+		t.Errorf("unexpected properties:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestGetSecretsPropertiesFormat(t *testing.T) {
+	body := secretResponseBody{}
+	body.Data.Secrets = []string{encryptSecret(t, "passcode", "B", "2"), encryptSecret(t, "passcode", "A", "x=1")}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+	plain, err := c.GetSecrets(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	request.Format = SecretsFormatProperties
+	rendered, err := c.GetSecrets(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "A=x\\=1\nB=2\n"; string(rendered.Body) != expected {
+		t.Errorf("unexpected body: expected %q, got %q", expected, rendered.Body)
+	}
+	if rendered.Fingerprint() != plain.Fingerprint() {
+		t.Errorf("expected the fingerprint of the API response, got %s and %s", rendered.Fingerprint(), plain.Fingerprint())
+	}
+
+	request.Format = "yaml"
+	if _, err := c.GetSecrets(context.Background(), request); err == nil || !strings.Contains(err.Error(), `unknown secrets format "yaml"`) {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+}
+
+func TestGetChangedSecrets(t *testing.T) {
+	current := map[string]string{"API": "key", "DB": "db"}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body := secretResponseBody{}
+		for key, value := range current {
+			body.Data.Secrets = append(body.Data.Secrets, encryptSecret(t, "passcode", key, value))
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+
+	first, err := c.GetChangedSecrets(context.Background(), request, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (Secrets{"API": "key", "DB": "db"}); !reflect.DeepEqual(first.Secrets, expected) {
+		t.Errorf("expected every secret on the first fetch, got %v", first.Secrets)
+	}
+
+	// Secrets are encrypted anew on every response, so only values count.
+	unchanged, err := c.GetChangedSecrets(context.Background(), request, first.Fingerprints)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unchanged.Secrets) != 0 || len(unchanged.Removed) != 0 {
+		t.Errorf("expected no changes, got %+v", unchanged)
+	}
+
+	current = map[string]string{"API": "rotated", "NEW": "value"}
+	changed, err := c.GetChangedSecrets(context.Background(), request, unchanged.Fingerprints)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (Secrets{"API": "rotated", "NEW": "value"}); !reflect.DeepEqual(changed.Secrets, expected) {
+		t.Errorf("unexpected changed secrets: expected %v, got %v", expected, changed.Secrets)
+	}
+	if expected := []string{"DB"}; !reflect.DeepEqual(changed.Removed, expected) {
+		t.Errorf("unexpected removed keys: expected %v, got %v", expected, changed.Removed)
+	}
+	if len(changed.Fingerprints) != 2 {
+		t.Errorf("expected fingerprints of the current secrets, got %v", changed.Fingerprints)
+	}
+}
+
+func TestListTeamMembers(t *testing.T) {
+	status := http.StatusOK
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/team/members" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = w.Write([]byte(`{"messages":["missing team:read scope"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"1","name":"Ada","email":"ada@example.com","role":"admin"},{"id":"2","email":"bob@example.com"}]}`))
+	})
+
+	members, err := c.ListTeamMembers(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []TeamMember{{ID: "1", Name: "Ada", Email: "ada@example.com", Role: "admin"}, {ID: "2", Email: "bob@example.com"}}
+	if !reflect.DeepEqual(members, expected) {
+		t.Errorf("unexpected members: expected %+v, got %+v", expected, members)
+	}
+
+	status = http.StatusForbidden
+	_, err = c.ListTeamMembers(context.Background())
+	if !errors.Is(err, ErrInsufficientScope) || !strings.Contains(err.Error(), "not allowed to list team members") {
+		t.Errorf("expected an insufficient scope error, got %v", err)
+	}
+}
+
+func TestListProjects(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"data":[{"title":"app","environments":[{"title":"development"},{"title":"production"}]}]}`))
+	})
+
+	projects, err := c.ListProjects(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Project{{Title: "app", Environments: []string{"development", "production"}}}
+	if !reflect.DeepEqual(projects, want) {
+		t.Errorf("unexpected projects: expected %v, got %v", want, projects)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	secret := encryptSecret(t, "passcode", "DB_PASSWORD", "s3cr3t")
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects":
+			w.WriteHeader(http.StatusForbidden)
+		case "/secrets":
+			_ = json.NewEncoder(w).Encode(secretResponseBody{Data: secretResponseBodyData{Secrets: []string{secret}}})
+		}
+	})
+
+	report := c.Diagnose(context.Background(), "app", "dev")
+	var results []string
+	for _, check := range report.Checks {
+		results = append(results, fmt.Sprintf("%s=%t", check.Name, check.Passed))
+	}
+	want := []string{"authenticate=true", "list projects=false", "fetch environment=true", "decrypt secret=true"}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("unexpected checks: expected %v, got %v", want, results)
+	}
+	if report.Passed() {
+		t.Errorf("expected report with a failed check not to pass")
+	}
+
+	out, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, sensitive := range []string{"s3cr3t", "DB_PASSWORD", "api-key", "passcode"} {
+		if strings.Contains(string(out), sensitive) {
+			t.Errorf("report leaks %q: %s", sensitive, out)
+		}
+	}
+}
+
+func TestVerifyPasscode(t *testing.T) {
+	var secrets []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(secretResponseBody{Data: secretResponseBodyData{Secrets: secrets}})
+	})
+	c.FallbackPasscodes = []string{"previous"}
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+
+	testCases := []struct {
+		label       string
+		secrets     []string
+		valid       bool
+		expectError string
+	}{
+		{label: "right passcode", secrets: []string{encryptSecret(t, "passcode", "A", "1")}, valid: true},
+		{label: "wrong passcode", secrets: []string{encryptSecret(t, "other", "A", "1")}},
+		{label: "malformed payload", secrets: []string{"not-base64!"}},
+		{label: "empty environment", expectError: "the environment holds no secret"},
+		{label: "fallback passcode", secrets: []string{encryptSecret(t, "previous", "A", "1")}, valid: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			secrets = tc.secrets
+			valid, err := c.VerifyPasscode(context.Background(), request)
+			if tc.expectError == "" && err != nil || tc.expectError != "" && (err == nil || !strings.Contains(err.Error(), tc.expectError)) {
+				t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if valid != tc.valid {
+				t.Errorf("expected valid to be %v, got %v", tc.valid, valid)
+			}
+		})
+	}
+}
+
+func TestDecryptPayload(t *testing.T) {
+	encrypted := encryptSecret(t, "passcode", "A", "1")
+	plaintext, err := decryptPayload("passcode", encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decrypted, err := decryptSecret(encrypted, "passcode")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(plaintext) != decrypted {
+		t.Errorf("expected %s, got %s", decrypted, plaintext)
+	}
+	zero(plaintext)
+	if !bytes.Equal(plaintext, make([]byte, len(plaintext))) {
+		t.Errorf("expected the plaintext to be zeroed, got %q", plaintext)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	secret := encryptSecret(t, "passcode", "DB_PASSWORD", "s3cr3t")
+	var requests int
+	authStatus := http.StatusOK
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/team/members":
+			w.WriteHeader(authStatus)
+		case "/secrets":
+			_ = json.NewEncoder(w).Encode(secretResponseBody{Data: secretResponseBodyData{Secrets: []string{secret}}})
+		}
+	})
+	clock := newFakeClock(time.Unix(1700000000, 0))
+	c.Clock = clock
+
+	status := c.Status(context.Background(), "app", "dev")
+	if !status.Reachable || !status.Authenticated || status.SecretCount != 1 || status.LastSuccess == nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.ReadCircuit != CircuitClosed || status.WriteCircuit != CircuitClosed {
+		t.Errorf("unexpected circuits: %+v", status)
+	}
+	out, err := json.Marshal(status)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, sensitive := range []string{"s3cr3t", "DB_PASSWORD", "api-key", "passcode"} {
+		if strings.Contains(string(out), sensitive) {
+			t.Errorf("status leaks %q: %s", sensitive, out)
+		}
+	}
+
+	authStatus = http.StatusUnauthorized
+	c.Status(context.Background(), "app", "dev")
+	if requests != 2 {
+		t.Errorf("expected a cached status, got %d requests", requests)
+	}
+
+	clock.Advance(StatusTTL)
+	status = c.Status(context.Background(), "app", "dev")
+	if !status.Reachable || status.Authenticated || status.SecretCount != -1 || status.Error == "" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if requests != 3 {
+		t.Errorf("expected the status to be probed again, got %d requests", requests)
+	}
+}
+
+func TestDiagnoseRedactsErrors(t *testing.T) {
+	c := &OnboardbaseClient{OnboardbaseAPIKey: "api-key", OnboardbasePassCode: "passcode"}
+	err := &APIError{Message: "rejected api-key", Data: `{"key":"A","value":"s3cr3t"}`}
+	if got, want := c.redactError(err), "Onboardbase API Client Error: rejected [redacted]"; got != want {
+		t.Errorf("unexpected error: expected %q, got %q", want, got)
+	}
+}
+
+func TestRequestHeaderPrecedence(t *testing.T) {
+	var got http.Header
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	})
+	c.ExtraHeaders = map[string]string{"X-Policy": "store", "X-Store": "1", HeaderAPIKey: "store-key"}
+
+	_, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{"X-Policy": "ref", "API_KEY": "ref-key"}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, want := range map[string]string{"X-Policy": "ref", "X-Store": "1", HeaderAPIKey: "api-key"} {
+		if values := got.Values(name); len(values) != 1 || values[0] != want {
+			t.Errorf("unexpected %s header: expected %q, got %q", name, want, values)
+		}
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	var got http.Header
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	})
+
+	for _, language := range []string{DefaultAcceptLanguage, "de-DE", ""} {
+		c.AcceptLanguage = language
+		if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, nil, nil, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var expected []string
+		if language != "" {
+			expected = []string{language}
+		}
+		if values := got.Values("Accept-Language"); !reflect.DeepEqual(values, expected) {
+			t.Errorf("unexpected Accept-Language header: expected %q, got %q", expected, values)
+		}
+	}
+}
+
+func TestLimitVerbosity(t *testing.T) {
+	testCases := []struct {
+		label  string
+		global int
+		limit  int
+		logged []string
+	}{
+		{label: "quieter than the controller", global: 2, limit: 0, logged: []string{"v0", "error"}},
+		{label: "not louder than the controller", global: 0, limit: 5, logged: []string{"v0", "error"}},
+		{label: "within the controller level", global: 2, limit: 1, logged: []string{"v0", "v1", "error"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			var logged []string
+			global := funcr.NewJSON(func(obj string) {
+				var entry struct {
+					Msg string `json:"msg"`
+				}
+				_ = json.Unmarshal([]byte(obj), &entry)
+				logged = append(logged, entry.Msg)
+			}, funcr.Options{Verbosity: tc.global})
+
+			logger := LimitVerbosity(global, tc.limit).WithName("store").WithValues("store", "a")
+			logger.Info("v0")
+			logger.V(1).Info("v1")
+			logger.V(2).Info("v2")
+			logger.Error(errors.New("failed"), "error")
+			if !reflect.DeepEqual(logged, tc.logged) {
+				t.Errorf("unexpected messages: expected %v, got %v", tc.logged, logged)
+			}
+		})
+	}
+}
+
+func TestStrictDecode(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"secrets":[],"cursor":"next"},"status":"ok"}`))
+	})
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+
+	if _, err := c.GetSecrets(context.Background(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.StrictDecode = true
+	_, err := c.GetSecrets(context.Background(), request)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "cursor"`) {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestNormalizeEnvelope(t *testing.T) {
+	payload := `{"environment":{"title":"dev"},"secrets":["s1"]}`
+	testCases := []struct {
+		label string
+		body  string
+	}{
+		{label: "canonical", body: `{"data":` + payload + `,"status":"success"}`},
+		{label: "capitalized envelope", body: `{"Data":` + payload + `,"Status":"success"}`},
+		{label: "result envelope", body: `{"result":` + payload + `,"status":"success"}`},
+		{label: "no envelope", body: payload},
+		{label: "single-element array", body: `{"data":[` + payload + `]}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := &OnboardbaseClient{}
+			var data secretResponseBody
+			if err := c.decodeResponse([]byte(tc.body), &data); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if data.Data.Environment.Title != "dev" || !reflect.DeepEqual(data.Data.Secrets, []string{"s1"}) {
+				t.Errorf("unexpected payload: %+v", data.Data)
+			}
+		})
+	}
+
+	t.Run("strict mode", func(t *testing.T) {
+		c := &OnboardbaseClient{StrictDecode: true}
+		var data secretResponseBody
+		if err := c.decodeResponse([]byte(`{"result":`+payload+`}`), &data); err == nil {
+			t.Errorf("expected strict decoding to reject a result envelope")
+		}
+	})
+}
+
+func FuzzGetSecretsFromPayload(f *testing.F) {
+	secret, err := EncryptSecret("passcode", "A", "1")
+	if err != nil {
+		f.Fatalf("unexpected error: %v", err)
+	}
+	f.Add(secret, false)
+	f.Add(secret[:len(secret)-4], false)
+	f.Add("U2FsdGVkX1"+strings.Repeat("A", 20), false)
+	f.Add("not-base64!", false)
+	f.Add(`{"key":"A","value":{"nested":[1,2]}}`, true)
+	f.Add(`{"key":1}`, true)
+	f.Add(`[`, true)
+
+	clients := map[bool]*OnboardbaseClient{}
+	for _, serverSide := range []bool{false, true} {
+		c, err := NewOnboardbaseClient("api-key", "passcode")
+		if err != nil {
+			f.Fatalf("unexpected error: %v", err)
+		}
+		c.ServerSideDecryption = serverSide
+		clients[serverSide] = c
+	}
+	f.Fuzz(func(t *testing.T, secret string, serverSide bool) {
+		kv, err := clients[serverSide].getSecretsFromPayload(secretResponseBodyData{Secrets: []string{secret}})
+		if err == nil && len(kv) != 1 {
+			t.Errorf("expected one secret, got %d", len(kv))
+		}
+	})
+}
+
+func FuzzDecodeResponse(f *testing.F) {
+	f.Add([]byte(`{"data":{"secrets":["{\"key\":\"A\",\"value\":\"1\"}"]}}`))
+	f.Add([]byte(`{"RESULT":[{"secrets":[]}],"status":"ok"}`))
+	f.Add([]byte(`{"secrets":null}`))
+	f.Add([]byte(`[[[[`))
+	f.Add([]byte{})
+
+	c, err := NewOnboardbaseClient("api-key", "passcode")
+	if err != nil {
+		f.Fatalf("unexpected error: %v", err)
+	}
+	c.ServerSideDecryption = true
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var data secretResponseBody
+		if c.decodeResponse(body, &data) != nil {
+			return
+		}
+		_, _ = c.getSecretsFromPayload(data.Data)
+	})
+}
+
+func TestReadBodyLimit(t *testing.T) {
+	c := &OnboardbaseClient{Clock: realClock{}}
+	if _, err := c.readBody(strings.NewReader(strings.Repeat("a", maxResponseBodySize+1)), func() {}); err == nil {
+		t.Errorf("expected an error for a body larger than %d bytes", maxResponseBodySize)
+	}
+	data, err := c.readBody(strings.NewReader("{}"), func() {})
+	if err != nil || string(data) != "{}" {
+		t.Errorf("unexpected body %q, error %v", data, err)
+	}
+}
+
+func TestDuplicateKeyPolicy(t *testing.T) {
+	data := secretResponseBodyData{Secrets: []string{
+		encryptSecret(t, "passcode", "A", "first"),
+		encryptSecret(t, "passcode", "A", "last"),
+	}}
+	testCases := []struct {
+		policy      DuplicateKeyPolicy
+		expected    string
+		expectError string
+	}{
+		{policy: DuplicateKeysLastWins, expected: "last"},
+		{policy: DuplicateKeysFirstWins, expected: "first"},
+		{policy: DuplicateKeysError, expectError: "secret A appears more than once"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			c := &OnboardbaseClient{OnboardbasePassCode: "passcode", SecretKeyField: "key", SecretValueField: "value", DuplicateKeyPolicy: tc.policy}
+			kv, err := c.getSecretsFromPayload(data)
+			if tc.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+					t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if kv["A"] != tc.expected {
+				t.Errorf("unexpected value: expected %q, got %q", tc.expected, kv["A"])
+			}
+		})
+	}
+}
+
+func TestKeepalive(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/team/members" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	c, err := NewOnboardbaseClient("api-key", "passcode")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock := newFakeClock(time.Unix(1700000000, 0))
+	c.Clock = clock
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.StartKeepalive(ctx, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Fatalf("expected requests to reuse a kept-alive connection, got %d connections", n)
+	}
+
+	for clock.waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+	// The keepalive waits again once the failed ping reset the connections.
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.waiting() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("keepalive did not ping")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&connections); n != 2 {
+		t.Errorf("expected a failed ping to reset the connections, got %d connections", n)
+	}
+}
+
+func TestServerSideDecryption(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(secretResponseBody{Data: secretResponseBodyData{Secrets: []string{`{"key":"A","value":"1"}`}}})
+	})
+	c.OnboardbasePassCode = ""
+	c.ServerSideDecryption = true
+
+	secret, err := c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "dev", Name: "A"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Value != "1" {
+		t.Errorf("unexpected value: expected %q, got %q", "1", secret.Value)
+	}
+}
+
+func TestRawPayloads(t *testing.T) {
+	payloads := []string{"plain text", `{"key":"A","value":"1"}`}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		secrets := make([]string, 0, len(payloads))
+		for _, payload := range payloads {
+			encrypted, err := encryptPayload("passcode", []byte(payload))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			secrets = append(secrets, encrypted)
+		}
+		_ = json.NewEncoder(w).Encode(secretResponseBody{Data: secretResponseBodyData{Secrets: secrets}})
+	})
+	c.RawPayloads = true
+
+	response, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(response.RawPayloads, payloads) {
+		t.Errorf("unexpected payloads: expected %q, got %q", payloads, response.RawPayloads)
+	}
+	if len(response.Secrets) != 0 {
+		t.Errorf("expected no parsed secrets, got %v", response.Secrets)
+	}
+}
+
+func TestGetSecretEmptyValue(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	server.SetSecrets("app", "dev", map[string]string{"EMPTY": ""})
+
+	c, err := server.Client()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		label            string
+		name             string
+		allowEmptyValues bool
+		expectNotFound   bool
+	}{
+		{label: "empty value not found by default", name: "EMPTY", expectNotFound: true},
+		{label: "empty value returned when allowed", name: "EMPTY", allowEmptyValues: true},
+		{label: "absent key", name: "ABSENT", expectNotFound: true},
+		{label: "absent key when empty values are allowed", name: "ABSENT", allowEmptyValues: true, expectNotFound: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c.AllowEmptyValues = tc.allowEmptyValues
+			secret, err := c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "dev", Name: tc.name})
+			if tc.expectNotFound {
+				if !errors.Is(err, ErrSecretNotFound) {
+					t.Errorf("expected secret not found, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if secret.Value != "" {
+				t.Errorf("unexpected value: expected empty, got %q", secret.Value)
+			}
+		})
+	}
+}
+
+func TestGraphQLFetcher(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	server.SetSecrets("app", "dev", map[string]string{"API": "key", "DB": "db"})
+
+	c, err := server.Client()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.SetGraphQL("", "")
+
+	response, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (Secrets{"API": "key", "DB": "db"}); !reflect.DeepEqual(response.Secrets, expected) {
+		t.Errorf("unexpected secrets: expected %v, got %v", expected, response.Secrets)
+	}
+	secret, err := c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "dev", Name: "DB"})
+	if err != nil || secret.Value != "db" {
+		t.Errorf("unexpected secret: %v, %v", secret, err)
+	}
+
+	_, err = c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "prod"})
+	if err == nil || !strings.Contains(err.Error(), "GraphQL query failed: environment not found") {
+		t.Errorf("expected the GraphQL error, got %v", err)
+	}
+}
+
+func TestUpdateSecretsWireFormat(t *testing.T) {
+	var body map[string]interface{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	if err := c.UpdateSecrets(context.Background(), UpdateSecretsRequest{Project: "app", Environment: "dev", Secrets: RawSecrets{{Key: "A", Value: "1"}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body["config"] != "dev" || body["environment"] != nil {
+		t.Errorf("expected the environment in the config field, got %v", body)
+	}
+}
+
+func TestUpdateSecretsCompression(t *testing.T) {
+	var encodings []string
+	var received []UpdateSecretsRequest
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			body = gz
+		}
+		var request UpdateSecretsRequest
+		_ = json.NewDecoder(body).Decode(&request)
+		received = append(received, request)
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	c.CompressThreshold = 100
+
+	small := UpdateSecretsRequest{Project: "app", Environment: "dev", Secrets: RawSecrets{{Key: "A", Value: "1"}}}
+	large := UpdateSecretsRequest{Project: "app", Environment: "dev", Secrets: RawSecrets{{Key: "B", Value: strings.Repeat("x", 200)}}}
+	for _, request := range []UpdateSecretsRequest{small, large} {
+		if err := c.UpdateSecrets(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if want := []string{"", "gzip"}; !reflect.DeepEqual(encodings, want) {
+		t.Errorf("expected encodings %q, got %q", want, encodings)
+	}
+	if want := []UpdateSecretsRequest{small, large}; !reflect.DeepEqual(received, want) {
+		t.Errorf("expected requests %v, got %v", want, received)
+	}
+}
+
+func TestUpdateSecretsCompressionRejected(t *testing.T) {
+	var encodings []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	c.CompressThreshold = 1
+
+	request := UpdateSecretsRequest{Project: "app", Environment: "dev", Secrets: RawSecrets{{Key: "A", Value: "1"}}}
+	for i := 0; i < 2; i++ {
+		if err := c.UpdateSecrets(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if want := []string{"gzip", "", ""}; !reflect.DeepEqual(encodings, want) {
+		t.Errorf("expected a single compressed attempt, got encodings %q", encodings)
+	}
+}
+
+func TestEncryptPushedSecrets(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	server.SetSecrets("app", "dev", map[string]string{"A": "1"})
+
+	c, err := server.Client()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.EncryptPushedSecrets = true
+	request := UpdateSecretsRequest{Project: "app", Environment: "dev", Secrets: RawSecrets{{Key: "TOKEN", Value: "s3cr3t"}}}
+	body, err := c.marshalUpdate(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(body), "s3cr3t") || strings.Contains(string(body), "TOKEN") {
+		t.Errorf("expected the pushed secret to be encrypted, got %s", body)
+	}
+
+	if err := c.UpdateSecrets(context.Background(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secrets, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Secrets{"A": "1", "TOKEN": "s3cr3t"}); !reflect.DeepEqual(secrets.Secrets, want) {
+		t.Errorf("unexpected secrets: expected %v, got %v", want, secrets.Secrets)
+	}
+
+	c.OnboardbasePassCode = ""
+	if err := c.UpdateSecrets(context.Background(), request); err == nil {
+		t.Errorf("expected an error without a passcode")
+	}
+}
+
+func TestTestServer(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	server.SetSecrets("app", "dev", map[string]string{"A": "1", "B": "2"})
+
+	c, err := server.Client()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Authenticate(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret, err := c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "dev", Name: "B"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Value != "2" {
+		t.Errorf("unexpected value: expected %q, got %q", "2", secret.Value)
+	}
+
+	secrets, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Secrets{"A": "1", "B": "2"}); !reflect.DeepEqual(secrets.Secrets, want) {
+		t.Errorf("unexpected secrets: expected %v, got %v", want, secrets.Secrets)
+	}
+
+	c.OnboardbaseAPIKey = "wrong"
+	if err := c.Authenticate(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
+
+func TestEnvironmentNotFound(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	server.SetSecrets("app", "dev", map[string]string{"A": "1"})
+	c, err := server.Client()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "stagin", Name: "A"})
+	var apiErr *APIError
+	if !errors.Is(err, ErrEnvironmentNotFound) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound ||
+		!strings.Contains(err.Error(), "project 'app' and environment 'stagin'") || !strings.Contains(err.Error(), "environment not found") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "ap", Environment: "dev"}); !errors.Is(err, ErrEnvironmentNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "dev", Name: "B"})
+	if !errors.Is(err, ErrSecretNotFound) || errors.Is(err, ErrEnvironmentNotFound) {
+		t.Errorf("unexpected error for a missing key: %v", err)
+	}
+}
+
+// TestConcurrentUse is meant to run with -race: reconcilers share a client
+// across goroutines, while a store update may reconfigure it.
+func TestConcurrentUse(t *testing.T) {
+	server := NewTestServer()
+	defer server.Close()
+	server.SetSecrets("app", "dev", map[string]string{"A": "1", "B": "2"})
+	c, err := server.Client()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	baseURL := c.BaseURL().String()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "dev", Name: "A"}); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}); err != nil {
+					errs <- err
+					return
+				}
+				c.LastSuccessfulSync("app", "dev")
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 10; j++ {
+			if err := c.SetBaseURL(baseURL); err != nil {
+				errs <- err
+				return
+			}
+			if err := c.SetFailoverURLs([]string{baseURL}); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSecretsResponseFingerprint(t *testing.T) {
+	secret := encryptSecret(t, "passcode", "A", "1")
+	body := fmt.Sprintf(`{"data":{"secrets":[%q]},"status":"ok"}`, secret)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	})
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+
+	var fingerprints []string
+	for i := 0; i < 2; i++ {
+		response, err := c.GetSecrets(context.Background(), request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fingerprints = append(fingerprints, response.Fingerprint())
+	}
+	if !strings.HasPrefix(fingerprints[0], "sha256:") || fingerprints[0] != fingerprints[1] {
+		t.Errorf("expected stable fingerprints, got %q", fingerprints)
+	}
+
+	reordered := &SecretsResponse{Body: []byte(fmt.Sprintf("{\n  \"status\": \"ok\",\n  \"data\": {\"secrets\": [%q]}\n}", secret))}
+	if fingerprint := reordered.Fingerprint(); fingerprint != fingerprints[0] {
+		t.Errorf("expected formatting not to change the fingerprint, got %q and %q", fingerprint, fingerprints[0])
+	}
+	other := &SecretsResponse{Body: []byte(fmt.Sprintf(`{"data":{"secrets":[%q]},"status":"ok"}`, encryptSecret(t, "passcode", "A", "2")))}
+	if other.Fingerprint() == fingerprints[0] {
+		t.Errorf("expected different payloads to have different fingerprints")
+	}
+	if fingerprint := (&SecretsResponse{}).Fingerprint(); fingerprint != "" {
+		t.Errorf("expected no fingerprint without a body, got %q", fingerprint)
+	}
+}
+
+func TestPresignedSecretsURL(t *testing.T) {
+	untrusted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to untrusted host: %s %s", r.Method, r.URL)
+	}))
+	defer untrusted.Close()
+
+	var payloadHeaders http.Header
+	payload := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payloadHeaders = r.Header
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, untrusted.URL+"/payload", http.StatusFound)
+			return
+		}
+		if r.URL.Query().Get("signature") != "abc" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]string{encryptSecret(t, "passcode", "A", "1")})
+	}))
+	defer payload.Close()
+	payloadURL, _ := url.Parse(payload.URL)
+
+	secretsURL := payload.URL + "/payload?signature=abc"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"data":{"secretsUrl":%q}}`, secretsURL)
+	})
+	c.ExtraHeaders = map[string]string{"X-Gateway": "token"}
+	request := SecretsRequest{Project: "app", Environment: "dev"}
+
+	if _, err := c.GetSecrets(context.Background(), request); err == nil || !strings.Contains(err.Error(), "neither the API host nor one of the allowed payload hosts") {
+		t.Errorf("unexpected error for a host that is not allowed: %v", err)
+	}
+
+	c.PayloadHosts = []string{payloadURL.Host}
+	response, err := c.GetSecrets(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Secrets{"A": "1"}); !reflect.DeepEqual(response.Secrets, want) {
+		t.Errorf("unexpected secrets: expected %v, got %v", want, response.Secrets)
+	}
+	for _, header := range []string{HeaderAPIKey, "X-Gateway"} {
+		if value := payloadHeaders.Get(header); value != "" {
+			t.Errorf("unexpected %s header sent to secrets URL: %q", header, value)
+		}
+	}
+
+	secretsURL = payload.URL + "/redirect"
+	if _, err := c.GetSecrets(context.Background(), request); err == nil || !strings.Contains(err.Error(), "refusing redirect") {
+		t.Errorf("unexpected error for a redirect to an untrusted host: %v", err)
+	}
+}
+
+func TestCircuitPerOperation(t *testing.T) {
+	var writes int
+	writesFail := true
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			return
+		}
+		writes++
+		if writesFail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	clock := newFakeClock(time.Unix(1700000000, 0))
+	c.Clock = clock
+	c.ReadCircuit = CircuitSettings{Threshold: 2, Cooldown: time.Minute}
+	c.WriteCircuit = CircuitSettings{Threshold: 2, Cooldown: time.Minute}
+	write := func() error {
+		_, err := c.performRequest(context.Background(), "/secrets", http.MethodPost, headers{}, nil, []byte(`{}`))
+		return err
+	}
+	read := func() error {
+		_, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := write(); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := write(); !errors.Is(err, ErrCircuitOpen) || writes != 2 {
+		t.Errorf("expected the write circuit to be open after %d writes, got %v", writes, err)
+	}
+	if err := read(); err != nil {
+		t.Errorf("unexpected error for a read: %v", err)
+	}
+	if state := c.CircuitState(OperationRead); state != CircuitClosed {
+		t.Errorf("unexpected read circuit state: %s", state)
+	}
+
+	// A failed probe reopens the circuit for another cooldown.
+	clock.Advance(time.Minute)
+	if err := write(); err == nil || errors.Is(err, ErrCircuitOpen) || c.CircuitState(OperationWrite) != CircuitOpen {
+		t.Errorf("unexpected probe error: %v, state %s", err, c.CircuitState(OperationWrite))
+	}
+
+	writesFail = false
+	clock.Advance(time.Minute)
+	if err := write(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if state := c.CircuitState(OperationWrite); state != CircuitClosed {
+		t.Errorf("expected a successful probe to close the write circuit, got %s", state)
+	}
+}
+
+func TestReadURL(t *testing.T) {
+	var primary, replica []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		primary = append(primary, r.Method)
+	})
+	replicaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderAPIKey) != "api-key" {
+			t.Errorf("expected the replica to receive the api key")
+		}
+		replica = append(replica, r.Method)
+	}))
+	defer replicaServer.Close()
+
+	if err := c.SetReadURL(replicaServer.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+		if _, err := c.performRequest(context.Background(), "/secrets", method, headers{}, nil, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := c.SetReadURL(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{http.MethodGet}; !reflect.DeepEqual(replica, want) {
+		t.Errorf("unexpected replica requests: expected %v, got %v", want, replica)
+	}
+	if want := []string{http.MethodPost, http.MethodDelete, http.MethodGet}; !reflect.DeepEqual(primary, want) {
+		t.Errorf("unexpected primary requests: expected %v, got %v", want, primary)
+	}
+}
+
+func TestSetCipherSuites(t *testing.T) {
+	c, err := NewOnboardbaseClient("api-key", "passcode")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.SetCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	suites := c.httpClient.Transport.(*http.Transport).TLSClientConfig.CipherSuites
+	if want := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}; !reflect.DeepEqual(suites, want) {
+		t.Errorf("unexpected cipher suites: expected %v, got %v", want, suites)
+	}
+
+	for name, expectError := range map[string]string{
+		"TLS_RSA_WITH_RC4_128_SHA": "cipher suite TLS_RSA_WITH_RC4_128_SHA is insecure",
+		"TLS_MADE_UP":              "unknown cipher suite TLS_MADE_UP",
+	} {
+		if err := c.SetCipherSuites([]string{name}); err == nil || err.Error() != expectError {
+			t.Errorf("unexpected error: %v, expected: %q", err, expectError)
+		}
+	}
+}
+
+func TestSetInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	c, err := NewOnboardbaseClient("api-key", "passcode")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil); err == nil {
+		t.Fatalf("expected the self-signed certificate to be rejected")
+	}
+	if err := c.SetInsecureSkipVerify(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil); err != nil {
+		t.Errorf("expected the request to succeed without verification, got %v", err)
+	}
+	if c.VerifyTLS {
+		t.Errorf("expected VerifyTLS to be false")
+	}
+}
+
+func TestBodyReadTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":`))
+		w.(http.Flusher).Flush()
+		<-release
+	})
+	clock := newFakeClock(time.Unix(1700000000, 0))
+	c.Clock = clock
+	c.BodyReadTimeout = 5 * time.Second
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- c.ping(context.Background())
+	}()
+	for clock.waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(5 * time.Second)
+
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "response body not read within 5s") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stalled response body was not aborted")
+	}
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	if err := c.SetResponseHeaderTimeout(50 * time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := c.ping(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timeout awaiting response headers") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSuccessCodes(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	c.SuccessCodes = map[string][]int{"POST /secrets": {http.StatusCreated, http.StatusNoContent}}
+
+	if _, err := c.performRequest(context.Background(), "/secrets", http.MethodGet, headers{}, nil, nil); err != nil {
+		t.Errorf("unexpected error for an undeclared operation: %v", err)
+	}
+	_, err := c.performRequest(context.Background(), "/secrets", http.MethodPost, headers{}, nil, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusOK || apiErr.Operation != "POST /secrets" ||
+		!strings.Contains(err.Error(), "expected status 201 or 204, got 200") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateSuccessCodes(t *testing.T) {
+	testCases := []struct {
+		label        string
+		successCodes map[string][]int
+		expectError  string
+	}{
+		{label: "valid", successCodes: map[string][]int{"POST /secrets": {200, 201}}},
+		{label: "missing path", successCodes: map[string][]int{"POST": {200}}, expectError: `invalid operation "POST"`},
+		{label: "lowercase method", successCodes: map[string][]int{"post /secrets": {200}}, expectError: `invalid operation "post /secrets"`},
+		{label: "no codes", successCodes: map[string][]int{"POST /secrets": {}}, expectError: "no success codes for operation POST /secrets"},
+		{label: "error code", successCodes: map[string][]int{"POST /secrets": {404}}, expectError: "success code 404 of operation POST /secrets is not a 2xx or 3xx status"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			err := ValidateSuccessCodes(tc.successCodes)
+			if (tc.expectError == "" && err != nil) || (tc.expectError != "" && (err == nil || !strings.Contains(err.Error(), tc.expectError))) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestCredentialFiles(t *testing.T) {
+	var apiKey string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get(HeaderAPIKey)
+	})
+	dir := t.TempDir()
+	apiKeyPath := filepath.Join(dir, "apiKey")
+	writeCredential := func(value string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(apiKeyPath, []byte(value), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.Chtimes(apiKeyPath, modTime, modTime); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := c.SetCredentialFiles(filepath.Join(dir, "missing"), ""); err == nil || !strings.Contains(err.Error(), "unable to read credential file") {
+		t.Errorf("unexpected error for a missing file: %v", err)
+	}
+	writeCredential("\n", time.Unix(1700000000, 0))
+	if err := c.SetCredentialFiles(apiKeyPath, ""); err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Errorf("unexpected error for an empty file: %v", err)
+	}
+
+	writeCredential("file-key\n", time.Unix(1700000000, 0))
+	if err := c.SetCredentialFiles(apiKeyPath, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expect := range []string{"file-key", "rotated-key"} {
+		if err := c.ping(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if apiKey != expect {
+			t.Errorf("unexpected api key: expected %q, got %q", expect, apiKey)
+		}
+		writeCredential("rotated-key", time.Unix(1700000060, 0))
+	}
+	if passCode := c.passCode(); passCode != "passcode" {
+		t.Errorf("unexpected passcode: expected %q, got %q", "passcode", passCode)
+	}
+}
+
+func TestLastSuccessfulSync(t *testing.T) {
+	fail := false
+	undecryptable := false
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body := secretResponseBody{}
+		if undecryptable {
+			body.Data.Secrets = []string{encryptSecret(t, "other-passcode", "A", "1")}
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	clock := newFakeClock(time.Unix(1700000000, 0))
+	c.Clock = clock
+
+	if _, ok := c.LastSuccessfulSync("app", "dev"); ok {
+		t.Fatalf("expected no successful sync yet")
+	}
+	if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	synced := clock.Now()
+
+	clock.Advance(time.Minute)
+	fail = true
+	if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if at, ok := c.LastSuccessfulSync("app", "dev"); !ok || !at.Equal(synced) {
+		t.Errorf("expected last sync at %s, got %s", synced, at)
+	}
+
+	clock.Advance(time.Minute)
+	fail, undecryptable = false, true
+	if _, err := c.GetSecrets(context.Background(), SecretsRequest{Project: "app", Environment: "dev"}); err == nil {
+		t.Fatalf("expected a decryption error")
+	}
+	if _, err := c.GetSecret(context.Background(), SecretRequest{Project: "app", Environment: "dev", Name: "A"}); err == nil {
+		t.Fatalf("expected a decryption error")
+	}
+	if at, ok := c.LastSuccessfulSync("app", "dev"); !ok || !at.Equal(synced) {
+		t.Errorf("expected a payload that failed to decrypt not to count as a sync, got last sync at %s", at)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	headerContentEncoding = "Content-Encoding"
+	contentEncodingGzip   = "gzip"
+)
+
+// compress returns body gzipped when compression is enabled, body reaches
+// CompressThreshold and the API has not rejected a compressed body before.
+func (c *OnboardbaseClient) compress(body []byte) ([]byte, bool, error) {
+	if c.CompressThreshold <= 0 || len(body) < c.CompressThreshold || c.compressionRejected.Load() {
+		return body, false, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, false, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// performCompressedRequest sends body gzipped when compress says so. A 415
+// response means the API does not accept the encoding: the request is sent
+// again uncompressed, and later requests are no longer compressed.
+func (c *OnboardbaseClient) performCompressedRequest(ctx context.Context, path, method string, reqHeaders headers, params queryParams, body []byte) (*apiResponse, error) {
+	compressed, ok, err := c.compress(body)
+	if err != nil {
+		return nil, &APIError{Err: err, Message: "unable to compress request body"}
+	}
+	if !ok {
+		return c.performRequest(ctx, path, method, reqHeaders, params, body)
+	}
+
+	gzipHeaders := make(headers, len(reqHeaders)+1)
+	for key, value := range reqHeaders {
+		gzipHeaders[key] = value
+	}
+	gzipHeaders[headerContentEncoding] = contentEncodingGzip
+	response, err := c.performRequest(ctx, path, method, gzipHeaders, params, compressed)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnsupportedMediaType {
+		return response, err
+	}
+	c.compressionRejected.Store(true)
+	c.logger().Info("the API rejected a gzip request body, sending request bodies uncompressed", "operation", method+" "+path)
+	return c.performRequest(ctx, path, method, reqHeaders, params, body)
+}
@@ -0,0 +1,124 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentialFile is a credential read from a file, e.g. one mounted by the
+// Secrets Store CSI driver. The file is checked whenever the credential is
+// used and re-read when it changed, so rotation takes effect without
+// recreating the client.
+type credentialFile struct {
+	path    string
+	mu      sync.Mutex
+	value   string
+	modTime time.Time
+	size    int64
+}
+
+func newCredentialFile(path string) (*credentialFile, error) {
+	f := &credentialFile{path: path}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// reload reads the file unless its modification time and size are unchanged.
+// The caller must hold mu, or own f.
+func (f *credentialFile) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("unable to read credential file: %w", err)
+	}
+	if f.value != "" && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("unable to read credential file: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return fmt.Errorf("credential file %s is empty", f.path)
+	}
+	f.value, f.modTime, f.size = value, info.ModTime(), info.Size()
+	return nil
+}
+
+// get returns the current credential. When the file cannot be read any
+// more, e.g. in the middle of a rotation, the last credential read is kept.
+func (f *credentialFile) get() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.reload(); err != nil {
+		log.Error(err, "keeping the last credential read", "path", f.path)
+	}
+	return f.value
+}
+
+// SetCredentialFiles makes the client read the API key and passcode from
+// files instead of OnboardbaseAPIKey and OnboardbasePassCode. An empty path
+// keeps the corresponding field. Both files must exist and be non-empty.
+func (c *OnboardbaseClient) SetCredentialFiles(apiKeyPath, passCodePath string) error {
+	var apiKeyFile, passCodeFile *credentialFile
+	var err error
+	if apiKeyPath != "" {
+		if apiKeyFile, err = newCredentialFile(apiKeyPath); err != nil {
+			return err
+		}
+	}
+	if passCodePath != "" {
+		if passCodeFile, err = newCredentialFile(passCodePath); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if apiKeyFile != nil {
+		c.apiKeyFile = apiKeyFile
+	}
+	if passCodeFile != nil {
+		c.passCodeFile = passCodeFile
+	}
+	return nil
+}
+
+func (c *OnboardbaseClient) apiKey() string {
+	c.mu.RLock()
+	apiKeyFile := c.apiKeyFile
+	c.mu.RUnlock()
+	if apiKeyFile != nil {
+		return apiKeyFile.get()
+	}
+	return c.OnboardbaseAPIKey
+}
+
+func (c *OnboardbaseClient) passCode() string {
+	c.mu.RLock()
+	passCodeFile := c.passCodeFile
+	c.mu.RUnlock()
+	if passCodeFile != nil {
+		return passCodeFile.get()
+	}
+	return c.OnboardbasePassCode
+}
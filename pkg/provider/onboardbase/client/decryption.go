@@ -0,0 +1,64 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// secretIDLength is the number of hex digits of a secret identifier.
+const secretIDLength = 12
+
+// secretID identifies an encrypted secret without revealing it: the first hex
+// digits of the SHA-256 of the ciphertext as the API returns it. The key of a
+// secret is encrypted along with its value, so a secret that cannot be
+// decrypted is only known by its ciphertext. The identifier stays the same
+// until the secret is changed in Onboardbase.
+func secretID(encrypted string) string {
+	sum := sha256.Sum256([]byte(encrypted))
+	return hex.EncodeToString(sum[:])[:secretIDLength]
+}
+
+// recordDecryptFailure counts and logs a secret of data that could not be
+// decrypted. The metric is labelled by environment only, so that its series
+// stay bounded; the secret identifier is left to the log.
+func (c *OnboardbaseClient) recordDecryptFailure(data secretResponseBodyData, encrypted string, err error) {
+	id := secretID(encrypted)
+	decryptFailures.WithLabelValues(c.BaseURL().Host, data.Project.Title, data.Environment.Title).Inc()
+	c.logger().Info("unable to decrypt secret, it may be encrypted with a different passcode", "secret", id, "project", data.Project.Title, "environment", data.Environment.Title, "error", err.Error())
+}
+
+// decryptWithFallback decrypts a secret of data with the passcode, then with
+// each of FallbackPasscodes. A fallback that succeeds means the secret was
+// not re-encrypted after a passcode rotation, so it is counted and logged.
+// The error of the passcode is returned when none succeeds.
+func (c *OnboardbaseClient) decryptWithFallback(data secretResponseBodyData, encrypted string) (string, error) {
+	decrypted, err := decryptSecret(encrypted, c.passCode())
+	if err == nil {
+		return decrypted, nil
+	}
+	for i, passcode := range c.FallbackPasscodes {
+		decrypted, fallbackErr := decryptSecret(encrypted, passcode)
+		if fallbackErr != nil {
+			continue
+		}
+		fallbackPasscodeDecrypts.WithLabelValues(c.BaseURL().Host, strconv.Itoa(i)).Inc()
+		c.logger().Info("decrypted secret with a fallback passcode, the passcode rotation is incomplete", "secret", secretID(encrypted), "fallback", i, "project", data.Project.Title, "environment", data.Environment.Title)
+		return decrypted, nil
+	}
+	return "", err
+}
@@ -0,0 +1,153 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const redacted = "[redacted]"
+
+// DiagnosticReport is the outcome of Diagnose. It never contains secret
+// values or credentials.
+type DiagnosticReport struct {
+	BaseURL     string            `json:"baseURL"`
+	Project     string            `json:"project"`
+	Environment string            `json:"environment"`
+	Checks      []DiagnosticCheck `json:"checks"`
+}
+
+// DiagnosticCheck is the result of a single Diagnose step.
+type DiagnosticCheck struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Skipped  bool          `json:"skipped,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Detail   string        `json:"detail,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Passed reports whether every check that ran passed.
+func (r *DiagnosticReport) Passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed && !check.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// Diagnose runs a series of checks against the API to troubleshoot a store:
+// authentication, listing projects, fetching the environment and decrypting
+// one of its secrets. Checks that depend on a failed one are skipped.
+func (c *OnboardbaseClient) Diagnose(ctx context.Context, project, environment string) *DiagnosticReport {
+	report := &DiagnosticReport{
+		BaseURL:     c.BaseURL().Redacted(),
+		Project:     project,
+		Environment: environment,
+	}
+
+	run := func(name string, check func() (string, error)) bool {
+		start := c.Clock.Now()
+		detail, err := check()
+		result := DiagnosticCheck{
+			Name:     name,
+			Passed:   err == nil,
+			Duration: c.Clock.Now().Sub(start),
+			Detail:   detail,
+		}
+		if err != nil {
+			result.Error = c.redactError(err)
+		}
+		report.Checks = append(report.Checks, result)
+		return err == nil
+	}
+	skip := func(names ...string) {
+		for _, name := range names {
+			report.Checks = append(report.Checks, DiagnosticCheck{Name: name, Skipped: true})
+		}
+	}
+
+	if !run("authenticate", func() (string, error) {
+		_, err := c.performRequest(ctx, "/team/members", "GET", headers{}, queryParams{}, httpRequestBody{})
+		return "", err
+	}) {
+		skip("list projects", "fetch environment", "decrypt secret")
+		return report
+	}
+
+	run("list projects", func() (string, error) {
+		projects, err := c.ListProjects(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d projects", len(projects)), nil
+	})
+
+	var data secretResponseBody
+	if !run("fetch environment", func() (string, error) {
+		request := SecretsRequest{Project: project, Environment: environment}
+		response, err := c.performRequest(ctx, "/secrets", "GET", headers{}, request.buildQueryParams(c.TokenScopedProject), httpRequestBody{})
+		if err != nil {
+			return "", err
+		}
+		if err := c.decodeResponse(response.Body, &data); err != nil {
+			return "", &APIError{Err: err, Message: "unable to unmarshal secret payload"}
+		}
+		if err := c.fetchPayload(ctx, &data.Data); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d secrets", len(data.Data.Secrets)), nil
+	}) {
+		skip("decrypt secret")
+		return report
+	}
+
+	if len(data.Data.Secrets) == 0 {
+		skip("decrypt secret")
+		return report
+	}
+	run("decrypt secret", func() (string, error) {
+		decrypted := data.Data.Secrets[0]
+		if !c.ServerSideDecryption {
+			var err error
+			decrypted, err = decryptSecret(decrypted, c.passCode())
+			if err != nil {
+				return "", &APIError{Err: err, Message: "unable to decrypt secret payload"}
+			}
+		}
+		if _, err := c.parseRawSecret(decrypted); err != nil {
+			return "", err
+		}
+		return "", nil
+	})
+	return report
+}
+
+// redactError renders err without the Data of an APIError, which may hold
+// decrypted payloads, and without the configured credentials.
+func (c *OnboardbaseClient) redactError(err error) string {
+	message := err.Error()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		withoutData := *apiErr
+		withoutData.Data = ""
+		message = withoutData.Error()
+	}
+	return c.redactCredentials(message)
+}
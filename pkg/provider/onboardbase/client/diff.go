@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// SecretsDiff lists, in sorted order, the keys whose values differ between
+// an environment and a desired set of secrets.
+type SecretsDiff struct {
+	// Added are keys that are desired but not in the environment.
+	Added []string
+	// Changed are keys in both whose values differ.
+	Changed []string
+	// Removed are keys in the environment that are not desired.
+	Removed []string
+}
+
+// Empty reports whether the environment already holds the desired secrets.
+func (d *SecretsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// DiffSecrets compares the secrets of an environment with desired, e.g. to
+// preview what pushing desired would change. It does not modify anything.
+func (c *OnboardbaseClient) DiffSecrets(ctx context.Context, request SecretsRequest, desired map[string]string) (*SecretsDiff, error) {
+	if request.AllEnvironments {
+		return nil, &APIError{Message: "cannot diff secrets across all environments"}
+	}
+	if c.RawPayloads {
+		return nil, &APIError{Message: "cannot diff secrets while raw payloads are enabled"}
+	}
+
+	response, err := c.getSecrets(ctx, request)
+	if err != nil {
+		return nil, &APIError{Err: err, Message: fmt.Sprintf("unable to fetch secrets of project '%s' and environment '%s'", request.Project, request.Environment)}
+	}
+	return diffSecrets(response.Secrets, desired), nil
+}
+
+func diffSecrets(current Secrets, desired map[string]string) *SecretsDiff {
+	diff := &SecretsDiff{}
+	for key, value := range desired {
+		currentValue, ok := current[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, key)
+		case currentValue != value:
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for key := range current {
+		if _, ok := desired[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Removed)
+	return diff
+}
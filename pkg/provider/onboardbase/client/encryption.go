@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5" //nolint:gosec // CryptoJS key derivation
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// encryptedUpdateSecretsRequest is the body of an update whose secrets the
+// client encrypted, each in the format the API returns secrets in.
+type encryptedUpdateSecretsRequest struct {
+	Secrets     []string `json:"secrets,omitempty"`
+	Project     string   `json:"project,omitempty"`
+	Environment string   `json:"config,omitempty"`
+}
+
+// marshalUpdate returns the body of an update, with its secrets encrypted
+// when EncryptPushedSecrets is set.
+func (c *OnboardbaseClient) marshalUpdate(request UpdateSecretsRequest) ([]byte, error) {
+	if !c.EncryptPushedSecrets {
+		return json.Marshal(request)
+	}
+	passcode := c.passCode()
+	if passcode == "" {
+		return nil, &APIError{Message: "cannot encrypt pushed secrets without a passcode"}
+	}
+	encrypted := encryptedUpdateSecretsRequest{Project: request.Project, Environment: request.Environment}
+	for _, secret := range request.Secrets {
+		payload, err := EncryptSecret(passcode, secret.Key, secret.Value)
+		if err != nil {
+			return nil, &APIError{Err: err, Message: "unable to encrypt secret " + secret.Key}
+		}
+		encrypted.Secrets = append(encrypted.Secrets, payload)
+	}
+	return json.Marshal(encrypted)
+}
+
+// EncryptSecret encrypts a secret the way the Onboardbase API does: the JSON
+// object {"key", "value"} in the CryptoJS passphrase format, i.e. OpenSSL
+// "Salted__" AES-256-CBC with an MD5 key derivation.
+func EncryptSecret(passcode, key, value string) (string, error) {
+	// RawSecret omits empty values, which the API sends as such.
+	plaintext, err := json.Marshal(map[string]string{defaultSecretKeyField: key, defaultSecretValueField: value})
+	if err != nil {
+		return "", err
+	}
+	return encryptPayload(passcode, plaintext)
+}
+
+// encryptPayload encrypts plaintext in the CryptoJS passphrase format.
+func encryptPayload(passcode string, plaintext []byte) (string, error) {
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	derived := deriveKey(passcode, salt)
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipherBlock, err := aes.NewCipher(derived[:32])
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(cipherBlock, derived[32:48]).CryptBlocks(ciphertext, plaintext)
+
+	payload := append(append([]byte(cryptoJSSaltPrefix), salt...), ciphertext...)
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// cryptoJSSaltPrefix starts every payload in the CryptoJS passphrase format.
+const cryptoJSSaltPrefix = "Salted__"
+
+// deriveKey derives the AES-256 key and CBC IV, 48 bytes in all, from a
+// passcode and salt the way CryptoJS does.
+func deriveKey(passcode string, salt []byte) []byte {
+	var derived, block []byte
+	for len(derived) < 48 {
+		h := md5.New() //nolint:gosec // CryptoJS key derivation
+		h.Write(block)
+		h.Write([]byte(passcode))
+		h.Write(salt)
+		block = h.Sum(nil)
+		derived = append(derived, block...)
+	}
+	return derived
+}
+
+// decryptPayload decrypts a payload in the CryptoJS passphrase format into a
+// byte slice, which, unlike a string, the caller can zero once done with it.
+func decryptPayload(passcode, payload string) ([]byte, error) {
+	salt, ciphertext, err := splitPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	derived := deriveKey(passcode, salt)
+	defer zero(derived)
+	cipherBlock, err := aes.NewCipher(derived[:32])
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(cipherBlock, derived[32:48]).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		zero(plaintext)
+		return nil, errors.New("invalid padding, the passcode may be wrong")
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// splitPayload decodes a payload in the CryptoJS passphrase format into its
+// salt and ciphertext, rejecting payloads that cannot be decrypted.
+func splitPayload(payload string) ([]byte, []byte, error) {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < len(cryptoJSSaltPrefix)+8+aes.BlockSize || string(data[:len(cryptoJSSaltPrefix)]) != cryptoJSSaltPrefix {
+		return nil, nil, errors.New("payload is not in the CryptoJS passphrase format")
+	}
+	salt, ciphertext := data[len(cryptoJSSaltPrefix):len(cryptoJSSaltPrefix)+8], data[len(cryptoJSSaltPrefix)+8:]
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, nil, errors.New("ciphertext is not a multiple of the block size")
+	}
+	return salt, ciphertext, nil
+}
+
+// zero overwrites b, so that sensitive bytes do not linger in memory.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
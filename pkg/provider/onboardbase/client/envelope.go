@@ -0,0 +1,87 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// envelopeFields are the fields the API has been seen to wrap the secrets
+// payload in, in order of preference.
+var envelopeFields = []string{"data", "result"}
+
+// normalizeEnvelope rewrites the variants of the secrets response envelope
+// seen from the API to the {"data": {...}} shape of secretResponseBody:
+//
+//   - the payload under "result" instead of "data", in any casing;
+//   - the payload without an envelope, i.e. "secrets" at the top level;
+//   - the payload wrapped in a single-element array.
+//
+// Bodies it does not recognize are returned unchanged.
+func normalizeEnvelope(body []byte) []byte {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(body, &top); err != nil {
+		return body
+	}
+
+	var payload json.RawMessage
+	for _, name := range envelopeFields {
+		if value, ok := lookupFold(top, name); ok {
+			payload = value
+			break
+		}
+	}
+	if payload == nil {
+		if _, ok := lookupFold(top, "secrets"); !ok {
+			return body
+		}
+		payload = body
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(payload), []byte("[")) {
+		var items []json.RawMessage
+		if err := json.Unmarshal(payload, &items); err == nil && len(items) == 1 {
+			payload = items[0]
+		}
+	}
+
+	normalized := map[string]json.RawMessage{"data": payload}
+	for _, name := range []string{"message", "status"} {
+		if value, ok := lookupFold(top, name); ok {
+			normalized[name] = value
+		}
+	}
+	out, err := json.Marshal(normalized)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// lookupFold returns the field of fields whose name equals name under
+// Unicode case-folding, like encoding/json matches struct fields.
+func lookupFold(fields map[string]json.RawMessage, name string) (json.RawMessage, bool) {
+	if value, ok := fields[name]; ok {
+		return value, true
+	}
+	for key, value := range fields {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
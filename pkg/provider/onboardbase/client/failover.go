@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetFailoverURLs configures the API base URLs tried, in order, when a
+// request to the base URL fails with a network error or a 5xx response.
+func (c *OnboardbaseClient) SetFailoverURLs(urlStrs []string) error {
+	failoverURLs := make([]*url.URL, 0, len(urlStrs))
+	for _, urlStr := range urlStrs {
+		failoverURL, err := url.Parse(strings.TrimSuffix(urlStr, "/"))
+		if err != nil {
+			return err
+		}
+		if failoverURL.Scheme == "" {
+			failoverURL.Scheme = "https"
+		}
+		failoverURLs = append(failoverURLs, failoverURL)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failoverURLs = failoverURLs
+	return nil
+}
+
+// shouldFailover reports whether a failed request may be retried against the
+// next host. Reads fail over freely; writes only when they carry an
+// idempotency key, so the server can deduplicate a request that reached it.
+func shouldFailover(ctx context.Context, method string, headers headers, err error) bool {
+	return canResend(ctx, method, headers) && IsUnavailable(err)
+}
+
+// shouldRetry reports whether a failed request is retried: when it could fail
+// over, when the API answered 429, or when its status code is one of
+// RetryableStatusCodes.
+func (c *OnboardbaseClient) shouldRetry(ctx context.Context, method string, headers headers, err error) bool {
+	if !canResend(ctx, method, headers) {
+		return false
+	}
+	if IsUnavailable(err) {
+		return true
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	for _, code := range c.RetryableStatusCodes {
+		if apiErr.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func canResend(ctx context.Context, method string, headers headers) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return method == http.MethodGet || headers[headerIdempotencyKey] != ""
+}
+
+// isNetworkError reports whether err comes from the transport rather than
+// from the client refusing the request, such as a rejected redirect.
+func isNetworkError(err error) bool {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(urlErr.Err, &netErr)
+}
+
+// IsUnavailable reports whether err means the API could not serve a request,
+// because it was unreachable, answered with a 5xx response, or the circuit
+// of the request was open.
+func IsUnavailable(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode >= http.StatusInternalServerError {
+		return true
+	}
+	return apiErr.StatusCode == 0 && isNetworkError(apiErr.Err)
+}
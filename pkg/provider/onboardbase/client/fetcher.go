@@ -0,0 +1,49 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// secretsFetcher fetches the secrets of an environment, decoded into the
+// shape of the REST /secrets response, along with the raw response body.
+type secretsFetcher interface {
+	fetchSecrets(ctx context.Context, c *OnboardbaseClient, headers headers, params queryParams) (*secretResponseBody, []byte, error)
+}
+
+// restFetcher fetches secrets from the REST /secrets endpoint. It is the
+// default.
+type restFetcher struct{}
+
+func (restFetcher) fetchSecrets(ctx context.Context, c *OnboardbaseClient, headers headers, params queryParams) (*secretResponseBody, []byte, error) {
+	response, err := c.performRequest(ctx, "/secrets", http.MethodGet, headers, params, httpRequestBody{})
+	if err != nil {
+		return nil, nil, environmentNotFound(err, params["project"], params["environment"])
+	}
+	var data secretResponseBody
+	if err := c.decodeResponse(response.Body, &data); err != nil {
+		return nil, nil, &APIError{Err: err, Message: "unable to unmarshal secret payload", Data: string(response.Body)}
+	}
+	return &data, response.Body, nil
+}
+
+func (c *OnboardbaseClient) secretsFetcher() secretsFetcher {
+	if c.fetcher == nil {
+		return restFetcher{}
+	}
+	return c.fetcher
+}
@@ -0,0 +1,60 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// fingerprintPrefix names the hash a fingerprint was computed with.
+const fingerprintPrefix = "sha256:"
+
+// Fingerprint identifies the raw response the secrets were decoded from
+// without revealing it, e.g. for audit logs recording which payload was
+// fetched. It hashes the body as returned by the API, where secrets are
+// still encrypted unless the API decrypts them. JSON bodies are normalised
+// first, so that identical payloads have the same fingerprint regardless of
+// whitespace or key order. A Body rendered in another Format is not
+// hashed. It is empty when the response has no body.
+func (r *SecretsResponse) Fingerprint() string {
+	body := r.Body
+	if r.apiBody != nil {
+		body = r.apiBody
+	}
+	if len(body) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(canonicalJSON(body))
+	return fingerprintPrefix + hex.EncodeToString(sum[:])
+}
+
+// canonicalJSON re-encodes data with sorted object keys and no insignificant
+// whitespace. Data that is not JSON is returned as is.
+func canonicalJSON(data []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return data
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return data
+	}
+	return canonical
+}
@@ -0,0 +1,107 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultGraphQLPath is the path of the GraphQL endpoint when
+	// SetGraphQL is given none.
+	DefaultGraphQLPath = "/graphql"
+
+	// DefaultGraphQLQuery is the query sent when SetGraphQL is given none.
+	// Its variables are the query parameters of the REST /secrets endpoint,
+	// and its result mirrors the data object of that endpoint.
+	DefaultGraphQLQuery = `query Secrets($project: String, $environment: String, $modifiedSince: String) {
+  secrets(project: $project, environment: $environment, modifiedSince: $modifiedSince) {
+    project { id title }
+    environment { id title }
+    team { id title }
+    secrets
+    secretsUrl
+  }
+}`
+)
+
+// graphQLFetcher fetches secrets with a GraphQL query, sent as a GET
+// request so that it is retried, hedged and routed to the read host like
+// any other read.
+type graphQLFetcher struct {
+	path  string
+	query string
+}
+
+type graphQLSecretsBody struct {
+	Data struct {
+		Secrets *secretResponseBodyData `json:"secrets"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+}
+
+// SetGraphQL makes the client fetch secrets from the GraphQL endpoint at
+// path with query instead of the REST /secrets endpoint. The result of
+// query must be a field named secrets. Other requests still use the REST
+// API.
+func (c *OnboardbaseClient) SetGraphQL(path, query string) {
+	if path == "" {
+		path = DefaultGraphQLPath
+	}
+	if query == "" {
+		query = DefaultGraphQLQuery
+	}
+	c.fetcher = graphQLFetcher{path: "/" + strings.TrimPrefix(path, "/"), query: query}
+}
+
+func (f graphQLFetcher) fetchSecrets(ctx context.Context, c *OnboardbaseClient, headers headers, params queryParams) (*secretResponseBody, []byte, error) {
+	variables, err := json.Marshal(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	response, err := c.performRequest(ctx, f.path, http.MethodGet, headers, queryParams{"query": f.query, "variables": string(variables)}, httpRequestBody{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var body graphQLSecretsBody
+	if err := json.Unmarshal(response.Body, &body); err != nil {
+		return nil, nil, &APIError{Err: err, Message: "unable to unmarshal GraphQL secrets response", Data: string(response.Body)}
+	}
+	if len(body.Errors) > 0 {
+		messages := make([]string, 0, len(body.Errors))
+		for _, graphQLErr := range body.Errors {
+			messages = append(messages, graphQLErr.Message)
+		}
+		return nil, nil, &APIError{
+			Message:   fmt.Sprintf("GraphQL query failed: %s", strings.Join(messages, "; ")),
+			Data:      string(response.Body),
+			Operation: http.MethodGet + " " + f.path,
+		}
+	}
+	if body.Data.Secrets == nil {
+		return nil, nil, &APIError{
+			Err:     ErrEnvironmentNotFound,
+			Message: fmt.Sprintf("no secrets found for project '%s' and environment '%s'; check that both exist and that the API key can access them", params["project"], params["environment"]),
+		}
+	}
+	return &secretResponseBody{Data: *body.Data.Secrets}, response.Body, nil
+}
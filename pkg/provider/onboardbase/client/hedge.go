@@ -0,0 +1,70 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+const (
+	hedgeWinnerPrimary = "primary"
+	hedgeWinnerHedge   = "hedge"
+)
+
+type hedgeResult struct {
+	response *apiResponse
+	err      error
+	winner   string
+}
+
+// performHedgedRequestTo sends a GET request and, when it has not completed
+// after HedgeDelay, a second identical one. The first successful response is
+// used and the other request is cancelled. Requests are not hedged while the
+// rate limiter is throttling, so hedging never spends a shrinking budget.
+func (c *OnboardbaseClient) performHedgedRequestTo(ctx context.Context, baseURL *url.URL, path, method string, headers headers, params queryParams, body httpRequestBody) (*apiResponse, error) {
+	if c.HedgeDelay <= 0 || method != "GET" || c.rateLimiter.delay(c.Clock.Now()) > 0 {
+		return c.performRequestTo(ctx, baseURL, path, method, headers, params, body)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The channel is buffered for both attempts so that the loser never
+	// blocks once its result is no longer read.
+	results := make(chan hedgeResult, 2)
+	attempt := func(winner string) {
+		response, err := c.performRequestTo(ctx, baseURL, path, method, headers, params, body)
+		results <- hedgeResult{response: response, err: err, winner: winner}
+	}
+
+	go attempt(hedgeWinnerPrimary)
+	select {
+	case result := <-results:
+		return result.response, result.err
+	case <-c.Clock.After(c.HedgeDelay):
+	}
+
+	hedgedRequests.WithLabelValues(baseURL.Host).Inc()
+	go attempt(hedgeWinnerHedge)
+	result := <-results
+	if result.err != nil {
+		if other := <-results; other.err == nil {
+			result = other
+		}
+	}
+	hedgeWins.WithLabelValues(baseURL.Host, result.winner).Inc()
+	return result.response, result.err
+}
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// StartKeepalive pings the API every interval until ctx is done, so that
+// connections dropped by intermediaries are noticed before a real request
+// needs them. Connections are otherwise closed after every request, so it
+// enables keep-alives on the transport, and must be called before the
+// client is shared. A failed ping closes the transport's idle connections,
+// making the next request dial a fresh one. It does nothing when interval
+// is not positive.
+func (c *OnboardbaseClient) StartKeepalive(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if transport, ok := c.httpClient.Transport.(*http.Transport); ok {
+		transport.DisableKeepAlives = false
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.Clock.After(interval):
+			}
+			if err := c.ping(ctx); err != nil && ctx.Err() == nil {
+				c.logger().Info("keepalive ping failed, resetting connections", "error", err.Error())
+				c.httpClient.CloseIdleConnections()
+			}
+		}
+	}()
+}
+
+func (c *OnboardbaseClient) ping(ctx context.Context) error {
+	_, err := c.performRequest(ctx, "/team/members", "GET", headers{}, queryParams{}, httpRequestBody{})
+	return err
+}
@@ -0,0 +1,51 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// syncTracker remembers when secrets were last fetched successfully from the
+// API, per project and environment.
+type syncTracker struct {
+	mu          sync.Mutex
+	lastSuccess map[[2]string]time.Time
+}
+
+func newSyncTracker() *syncTracker {
+	return &syncTracker{lastSuccess: map[[2]string]time.Time{}}
+}
+
+func (t *syncTracker) record(project, environment string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastSuccess[[2]string{project, environment}] = at
+}
+
+func (t *syncTracker) get(project, environment string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at, ok := t.lastSuccess[[2]string{project, environment}]
+	return at, ok
+}
+
+// LastSuccessfulSync returns when secrets of the given project and environment
+// were last fetched successfully from the API. It is only updated by API
+// responses that were fetched and parsed without error.
+func (c *OnboardbaseClient) LastSuccessfulSync(project, environment string) (time.Time, bool) {
+	return c.syncTracker.get(project, environment)
+}
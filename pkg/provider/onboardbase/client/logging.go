@@ -0,0 +1,75 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/go-logr/logr"
+)
+
+// LimitVerbosity returns a logger that drops messages more verbose than
+// level, e.g. to keep the debug logs of one store out of a busy controller.
+// Messages must still pass the verbosity of logger, so the result can only
+// be quieter. Errors are always logged.
+func LimitVerbosity(logger logr.Logger, level int) logr.Logger {
+	sink := logger.GetSink()
+	if sink == nil {
+		return logger
+	}
+	if withDepth, ok := sink.(logr.CallDepthLogSink); ok {
+		// Account for the frame of the wrapping sink.
+		sink = withDepth.WithCallDepth(1)
+	}
+	return logger.WithSink(&verbositySink{LogSink: sink, level: level})
+}
+
+// verbositySink is a LogSink enabled up to a verbosity level.
+type verbositySink struct {
+	logr.LogSink
+	level int
+}
+
+func (s *verbositySink) Enabled(level int) bool {
+	return level <= s.level && s.LogSink.Enabled(level)
+}
+
+func (s *verbositySink) Info(level int, msg string, keysAndValues ...interface{}) {
+	if level <= s.level {
+		s.LogSink.Info(level, msg, keysAndValues...)
+	}
+}
+
+func (s *verbositySink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &verbositySink{LogSink: s.LogSink.WithValues(keysAndValues...), level: s.level}
+}
+
+func (s *verbositySink) WithName(name string) logr.LogSink {
+	return &verbositySink{LogSink: s.LogSink.WithName(name), level: s.level}
+}
+
+func (s *verbositySink) WithCallDepth(depth int) logr.LogSink {
+	withDepth, ok := s.LogSink.(logr.CallDepthLogSink)
+	if !ok {
+		return s
+	}
+	return &verbositySink{LogSink: withDepth.WithCallDepth(depth), level: s.level}
+}
+
+// logger returns Log, or the provider's logger when Log is unset.
+func (c *OnboardbaseClient) logger() logr.Logger {
+	if c.Log.GetSink() == nil {
+		return log
+	}
+	return c.Log
+}
@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	providermetrics "github.com/external-secrets/external-secrets/pkg/provider/metrics"
+)
+
+var (
+	rateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_ratelimit_remaining",
+		Help:      "Remaining Onboardbase API requests in the current rate limit window",
+	}, []string{"host"})
+
+	rateLimitReset = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_ratelimit_reset_seconds",
+		Help:      "Seconds until the Onboardbase API rate limit window resets",
+	}, []string{"host"})
+
+	hedgedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_hedged_requests_total",
+		Help:      "Onboardbase API reads for which a hedge request was sent",
+	}, []string{"host"})
+
+	hedgeWins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_hedge_wins_total",
+		Help:      "Hedged Onboardbase API reads by the request whose response was used",
+	}, []string{"host", "winner"})
+
+	circuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_circuit_state",
+		Help:      "State of the Onboardbase API circuit breaker per operation: 0 closed, 1 half-open, 2 open",
+	}, []string{"host", "operation"})
+
+	circuitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_circuit_rejections_total",
+		Help:      "Onboardbase API requests not sent because the circuit of their operation was open",
+	}, []string{"host", "operation"})
+
+	decryptFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_decrypt_failures_total",
+		Help:      "Onboardbase secrets that could not be decrypted, by project and environment",
+	}, []string{"host", "project", "environment"})
+
+	fallbackPasscodeDecrypts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: providermetrics.ExternalSecretSubsystem,
+		Name:      "provider_onboardbase_fallback_passcode_decrypts_total",
+		Help:      "Onboardbase secrets decrypted with a fallback passcode, by the position of the passcode in the fallback list",
+	}, []string{"host", "fallback"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(rateLimitRemaining, rateLimitReset, hedgedRequests, hedgeWins, circuitState, circuitRejections, decryptFailures, fallbackPasscodeDecrypts)
+}
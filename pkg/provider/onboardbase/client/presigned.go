@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// fetchPayload replaces a secrets payload that only references a
+// pre-signed URL with the encrypted secrets served there, as a JSON array
+// of strings. The URL must use the API's scheme and point to the API host
+// or one of PayloadHosts. It carries its own authorization, so none of the
+// client's credentials or headers are sent with it.
+func (c *OnboardbaseClient) fetchPayload(ctx context.Context, data *secretResponseBodyData) error {
+	if data.SecretsURL == "" {
+		return nil
+	}
+	if len(data.Secrets) > 0 {
+		return &APIError{Message: "the API returned both secrets and a secrets URL"}
+	}
+
+	payloadURL, err := url.Parse(data.SecretsURL)
+	if err != nil {
+		return &APIError{Err: err, Message: "invalid secrets URL"}
+	}
+	if err := c.checkPayloadURL(payloadURL); err != nil {
+		return &APIError{Err: err, Message: "refusing to fetch secrets URL"}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, payloadURL.String(), http.NoBody)
+	if err != nil {
+		return &APIError{Err: err, Message: "unable to form HTTP request"}
+	}
+	req.Header.Set("accept", "application/json")
+	req.Header.Set("user-agent", c.UserAgent)
+
+	r, err := c.httpClient.Do(req)
+	if err != nil {
+		return &APIError{Err: err, Message: "unable to load secrets URL"}
+	}
+	defer r.Body.Close()
+	body, err := c.readBody(r.Body, cancel)
+	if err != nil {
+		return &APIError{Err: err, Message: "unable to read entire secrets URL response"}
+	}
+	if r.StatusCode != http.StatusOK {
+		return &APIError{Err: fmt.Errorf("%d status code; %d bytes", r.StatusCode, len(body)), Message: "unable to load secrets URL", StatusCode: r.StatusCode}
+	}
+
+	var secrets []string
+	if err := json.Unmarshal(body, &secrets); err != nil {
+		return &APIError{Err: err, Message: "unable to unmarshal secrets URL payload", Data: string(body)}
+	}
+	data.Secrets = secrets
+	data.SecretsURL = ""
+	return nil
+}
+
+// checkPayloadURL accepts URLs on the scheme of the API host serving reads
+// whose host is that API host or one of PayloadHosts.
+func (c *OnboardbaseClient) checkPayloadURL(payloadURL *url.URL) error {
+	apiURL := c.urlFor(http.MethodGet)
+	if payloadURL.Scheme != apiURL.Scheme {
+		return fmt.Errorf("secrets URL must use %s, not %q", apiURL.Scheme, payloadURL.Scheme)
+	}
+	if payloadURL.Host == apiURL.Host {
+		return nil
+	}
+	for _, host := range c.PayloadHosts {
+		if payloadURL.Host == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s is neither the API host nor one of the allowed payload hosts", payloadURL.Host)
+}
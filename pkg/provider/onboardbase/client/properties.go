@@ -0,0 +1,107 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// SecretsFormat is a format GetSecrets renders secrets in.
+type SecretsFormat string
+
+// SecretsFormatProperties renders secrets as a Java .properties file.
+const SecretsFormatProperties SecretsFormat = "properties"
+
+// render replaces the body of response with its secrets in format, keeping
+// the response of the API for Fingerprint.
+func (r *SecretsResponse) render(format SecretsFormat) error {
+	switch format {
+	case "":
+		return nil
+	case SecretsFormatProperties:
+		if r.RawPayloads != nil {
+			return &APIError{Message: "raw payloads cannot be rendered as properties"}
+		}
+		r.apiBody = r.Body
+		r.Body = MarshalProperties(r.Secrets)
+		return nil
+	default:
+		return &APIError{Message: fmt.Sprintf("unknown secrets format %q", format)}
+	}
+}
+
+// MarshalProperties renders secrets as a Java .properties file, one
+// key=value line per secret sorted by key, without the timestamp comment
+// java.util.Properties writes, so that equal secrets render equally. Keys
+// and values are escaped as Properties.store does, with characters outside
+// printable ASCII written as \uXXXX escapes, so the file also loads with
+// the ISO 8859-1 encoding older readers assume.
+func MarshalProperties(secrets Secrets) []byte {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		writePropertiesString(&b, key, true)
+		b.WriteByte('=')
+		writePropertiesString(&b, secrets[key], false)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// writePropertiesString escapes s for a key or a value. All spaces of a key
+// are escaped, but only a leading space of a value.
+func writePropertiesString(b *strings.Builder, s string, isKey bool) {
+	for i, r := range s {
+		switch r {
+		case ' ':
+			if isKey || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(' ')
+		case '\\', '=', ':', '#', '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r >= 0x20 && r <= 0x7e {
+				b.WriteRune(r)
+				continue
+			}
+			// Properties escape UTF-16 code units, so characters outside
+			// the Basic Multilingual Plane take a surrogate pair.
+			if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(b, `\u%04X\u%04X`, r1, r2)
+				continue
+			}
+			fmt.Fprintf(b, `\u%04X`, r)
+		}
+	}
+}
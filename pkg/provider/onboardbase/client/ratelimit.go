@@ -0,0 +1,107 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+
+	// rateLimitSlowdownThreshold is the remaining budget below which requests
+	// are spread out over the time left until the limit resets.
+	rateLimitSlowdownThreshold = 10
+
+	// resetEpochCutoff separates X-RateLimit-Reset values given as a unix
+	// timestamp from values given as seconds until the reset.
+	resetEpochCutoff = 1_000_000_000
+)
+
+// rateLimiter throttles requests proactively based on the rate limit headers
+// returned by the Onboardbase API. It is disabled until both headers are seen.
+type rateLimiter struct {
+	mu        sync.Mutex
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{}
+}
+
+// observe records the rate limit state reported by a response. Missing or
+// malformed headers disable throttling until valid values are seen again.
+func (l *rateLimiter) observe(host string, header http.Header, now time.Time) {
+	remaining, errRemaining := strconv.Atoi(header.Get(headerRateLimitRemaining))
+	reset, errReset := strconv.ParseInt(header.Get(headerRateLimitReset), 10, 64)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if errRemaining != nil || errReset != nil {
+		l.known = false
+		return
+	}
+
+	l.known = true
+	l.remaining = remaining
+	if reset >= resetEpochCutoff {
+		l.reset = time.Unix(reset, 0)
+	} else {
+		l.reset = now.Add(time.Duration(reset) * time.Second)
+	}
+
+	rateLimitRemaining.WithLabelValues(host).Set(float64(remaining))
+	rateLimitReset.WithLabelValues(host).Set(l.reset.Sub(now).Seconds())
+}
+
+// delay returns how long the next request should wait so that the remaining
+// budget is spread evenly until the limit resets.
+func (l *rateLimiter) delay(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.known || l.remaining > rateLimitSlowdownThreshold || !l.reset.After(now) {
+		return 0
+	}
+
+	window := l.reset.Sub(now)
+	if l.remaining <= 0 {
+		return window
+	}
+	return window / time.Duration(l.remaining+1)
+}
+
+// wait blocks for the current throttling delay or until ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, clock Clock) error {
+	d := l.delay(clock.Now())
+	if d <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clock.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetReadURL sends reads to a replica of the API at urlStr instead of the
+// base URL, which keeps serving writes. Reads go to the base URL again when
+// urlStr is empty.
+func (c *OnboardbaseClient) SetReadURL(urlStr string) error {
+	var readURL *url.URL
+	if urlStr != "" {
+		var err error
+		readURL, err = url.Parse(strings.TrimSuffix(urlStr, "/"))
+		if err != nil {
+			return err
+		}
+		if readURL.Scheme == "" {
+			readURL.Scheme = "https"
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readURL = readURL
+	return nil
+}
+
+// urlFor returns the URL requests of method are sent to first: the read URL
+// for reads when one is set, the base URL otherwise.
+func (c *OnboardbaseClient) urlFor(method string) *url.URL {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if method == http.MethodGet && c.readURL != nil {
+		u := *c.readURL
+		return &u
+	}
+	u := *c.baseURL
+	return &u
+}
@@ -0,0 +1,45 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxRedirects caps how many redirects a single API request may follow.
+const maxRedirects = 3
+
+// authHeaders are the request headers carrying credentials.
+var authHeaders = []string{HeaderAPIKey, "Authorization"}
+
+// checkRedirect only allows redirects that stay on the API host and scheme
+// the request was sent to. net/http forwards custom headers such as api_key
+// to any host, so credentials are stripped before a cross-host redirect is
+// refused.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	origin := via[0].URL
+	if req.URL.Host != origin.Host || req.URL.Scheme != origin.Scheme {
+		for _, header := range authHeaders {
+			req.Header.Del(header)
+		}
+		return fmt.Errorf("refusing redirect from %s to %s: redirects must stay on %s://%s",
+			via[len(via)-1].URL.Host, req.URL.Redacted(), origin.Scheme, origin.Host)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// DefaultSigningHeader carries the request signature when SigningHeader is
+// not set.
+const DefaultSigningHeader = "X-Signature"
+
+// SigningTimestampHeader carries the Unix time, in seconds, at which a signed
+// request was sent. It is part of the signature, so that gateways can refuse
+// replays of old requests.
+const SigningTimestampHeader = "X-Signature-Timestamp"
+
+// DefaultSigningMaxBodySize is the largest request body signed when
+// SigningMaxBodySize is not set.
+const DefaultSigningMaxBodySize = 1 << 20
+
+// signRequest returns the hex-encoded HMAC-SHA256 of method, path, the
+// encoded query, timestamp and body, separated by newlines, under key. The
+// query is signed so that a signature cannot be replayed against another
+// project or environment.
+func signRequest(key []byte, method, path, query, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(method + "\n" + path + "\n" + query + "\n" + timestamp + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *OnboardbaseClient) signingHeader() string {
+	if c.SigningHeader == "" {
+		return DefaultSigningHeader
+	}
+	return c.SigningHeader
+}
+
+// checkSigningBodySize rejects a body too large to be signed, before the
+// request is sent.
+func (c *OnboardbaseClient) checkSigningBodySize(body []byte) error {
+	limit := c.SigningMaxBodySize
+	if limit <= 0 {
+		limit = DefaultSigningMaxBodySize
+	}
+	if len(body) > limit {
+		return &APIError{Message: fmt.Sprintf("request body of %d bytes exceeds the %d bytes that can be signed", len(body), limit)}
+	}
+	return nil
+}
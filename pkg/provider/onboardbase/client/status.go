@@ -0,0 +1,122 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// StatusTTL is how long Status serves a computed status before probing the
+// API again.
+const StatusTTL = 15 * time.Second
+
+// StoreStatus summarizes the health of a store for dashboards. It never
+// contains secret values or credentials.
+type StoreStatus struct {
+	// Reachable is whether the API answered, even with an error.
+	Reachable bool `json:"reachable"`
+	// Authenticated is whether the API accepted the credentials.
+	Authenticated bool `json:"authenticated"`
+	// SecretCount is the number of secrets in the environment, or -1 when
+	// they could not be fetched.
+	SecretCount int `json:"secretCount"`
+	// LastSuccess is when secrets of the environment were last fetched
+	// successfully, if ever.
+	LastSuccess  *time.Time   `json:"lastSuccess,omitempty"`
+	ReadCircuit  CircuitState `json:"readCircuit"`
+	WriteCircuit CircuitState `json:"writeCircuit"`
+	CheckedAt    time.Time    `json:"checkedAt"`
+	// Error is the redacted error of the first probe that failed.
+	Error string `json:"error,omitempty"`
+}
+
+// statusCache remembers the last status of each environment for StatusTTL,
+// so that dashboards polling many stores do not cause a storm of probes.
+// Concurrent calls for an environment share one probe.
+type statusCache struct {
+	mu       sync.Mutex
+	statuses map[[2]string]StoreStatus
+	probes   singleflight.Group
+}
+
+func (s *statusCache) get(key [2]string, now time.Time) (StoreStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status, ok := s.statuses[key]
+	if !ok || now.Sub(status.CheckedAt) >= StatusTTL {
+		return StoreStatus{}, false
+	}
+	return status, true
+}
+
+func (s *statusCache) set(key [2]string, status StoreStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.statuses == nil {
+		s.statuses = map[[2]string]StoreStatus{}
+	}
+	s.statuses[key] = status
+}
+
+// Status reports whether the API is reachable and accepts the credentials,
+// how many secrets the environment holds, when they were last fetched and
+// the state of the circuits. It is composed of the Authenticate and
+// GetSecrets probes, and computed at most once per StatusTTL.
+func (c *OnboardbaseClient) Status(ctx context.Context, project, environment string) StoreStatus {
+	key := [2]string{project, environment}
+	if status, ok := c.statuses.get(key, c.Clock.Now()); ok {
+		return status
+	}
+	result, _, _ := c.statuses.probes.Do(project+"/"+environment, func() (interface{}, error) {
+		status := c.probeStatus(ctx, project, environment)
+		c.statuses.set(key, status)
+		return status, nil
+	})
+	return result.(StoreStatus)
+}
+
+func (c *OnboardbaseClient) probeStatus(ctx context.Context, project, environment string) (status StoreStatus) {
+	status.SecretCount = -1
+	defer func() {
+		if at, ok := c.LastSuccessfulSync(project, environment); ok {
+			status.LastSuccess = &at
+		}
+		status.ReadCircuit = c.CircuitState(OperationRead)
+		status.WriteCircuit = c.CircuitState(OperationWrite)
+		status.CheckedAt = c.Clock.Now()
+	}()
+
+	if err := c.Authenticate(ctx); err != nil {
+		var apiErr *APIError
+		status.Reachable = errors.As(err, &apiErr) && apiErr.StatusCode != 0
+		status.Error = c.redactError(err)
+		return status
+	}
+	status.Reachable = true
+	status.Authenticated = true
+
+	response, err := c.GetSecrets(ctx, SecretsRequest{Project: project, Environment: environment})
+	if err != nil {
+		status.Error = c.redactError(err)
+		return status
+	}
+	status.SecretCount = len(response.Secrets)
+	return status
+}
@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateSuccessCodes checks that operations are of the form "METHOD /path",
+// e.g. "POST /secrets", and that the codes are ones the client can treat as
+// a success.
+func ValidateSuccessCodes(successCodes map[string][]int) error {
+	for operation, codes := range successCodes {
+		method, path, ok := strings.Cut(operation, " ")
+		if !ok || method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("invalid operation %q: expected a method and a path, e.g. \"POST /secrets\"", operation)
+		}
+		if len(codes) == 0 {
+			return fmt.Errorf("no success codes for operation %s", operation)
+		}
+		for _, code := range codes {
+			if !isSuccess(code) {
+				return fmt.Errorf("success code %d of operation %s is not a 2xx or 3xx status", code, operation)
+			}
+		}
+	}
+	return nil
+}
+
+// checkSuccessCode rejects a successful status that is not one of the
+// SuccessCodes declared for the operation. Operations without declared
+// codes accept any successful status.
+func (c *OnboardbaseClient) checkSuccessCode(method, path string, statusCode int) error {
+	codes, ok := c.SuccessCodes[method+" "+path]
+	if !ok {
+		return nil
+	}
+	for _, code := range codes {
+		if code == statusCode {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected status %s, got %d", joinCodes(codes), statusCode)
+}
+
+func joinCodes(codes []int) string {
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprint(code)
+	}
+	return strings.Join(parts, " or ")
+}
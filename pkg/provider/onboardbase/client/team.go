@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrInsufficientScope is wrapped by the error ListTeamMembers returns when
+// the API key may not read the team.
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// TeamMember is a member of the team the API key belongs to.
+type TeamMember struct {
+	ID    string
+	Name  string
+	Email string
+	Role  string
+}
+
+type teamMembersResponseBody struct {
+	Data []teamMemberResponseBodyData `json:"data,omitempty"`
+}
+
+type teamMemberResponseBodyData struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+// ListTeamMembers returns the members of the team the API key belongs to,
+// from the endpoint Authenticate probes.
+func (c *OnboardbaseClient) ListTeamMembers(ctx context.Context) ([]TeamMember, error) {
+	response, err := c.performRequest(ctx, "/team/members", "GET", headers{}, queryParams{}, httpRequestBody{})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden {
+			forbidden := *apiErr
+			forbidden.Message = "the API key is not allowed to list team members; grant it access to the team"
+			forbidden.Err = ErrInsufficientScope
+			if apiErr.Err == nil && apiErr.Message != "" {
+				forbidden.Err = fmt.Errorf("%w: %s", ErrInsufficientScope, apiErr.Message)
+			}
+			return nil, &forbidden
+		}
+		return nil, err
+	}
+
+	var data teamMembersResponseBody
+	if err := json.Unmarshal(response.Body, &data); err != nil {
+		return nil, &APIError{Err: err, Message: "unable to unmarshal team members payload", Data: string(response.Body)}
+	}
+
+	members := make([]TeamMember, 0, len(data.Data))
+	for _, member := range data.Data {
+		members = append(members, TeamMember(member))
+	}
+	return members, nil
+}
@@ -0,0 +1,202 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+const (
+	// TestServerAPIKey is the API key accepted by a TestServer.
+	TestServerAPIKey = "test-api-key"
+	// TestServerPasscode is the passcode a TestServer encrypts secrets with.
+	TestServerPasscode = "test-passcode"
+)
+
+// TestServer emulates the /team/members and /secrets endpoints of the
+// Onboardbase API, and secrets queries on DefaultGraphQLPath, serving secrets encrypted the way the real API does and
+// storing pushed ones. It is meant for tests exercising OnboardbaseClient
+// end to end.
+type TestServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	secrets map[[2]string]map[string]string
+}
+
+// NewTestServer starts a TestServer without any secrets. Callers must Close it.
+func NewTestServer() *TestServer {
+	s := &TestServer{secrets: map[[2]string]map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/team/members", s.authenticated(s.handleTeamMembers))
+	mux.HandleFunc("/secrets", s.authenticated(s.handleSecrets))
+	mux.HandleFunc(DefaultGraphQLPath, s.authenticated(s.handleGraphQL))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// SetSecrets replaces the secrets of an environment.
+func (s *TestServer) SetSecrets(project, environment string, secrets map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[[2]string{project, environment}] = secrets
+}
+
+// Client returns an OnboardbaseClient talking to the server.
+func (s *TestServer) Client() (*OnboardbaseClient, error) {
+	c, err := NewOnboardbaseClient(TestServerAPIKey, TestServerPasscode)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.SetBaseURL(s.URL); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *TestServer) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderAPIKey) != TestServerAPIKey {
+			writeTestError(w, http.StatusUnauthorized, "invalid api key")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *TestServer) handleTeamMembers(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	_, _ = w.Write([]byte(`{"data":[]}`))
+}
+
+func (s *TestServer) handleSecrets(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.handleUpdateSecrets(w, r)
+		return
+	default:
+		writeTestError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	project, environment := r.URL.Query().Get("project"), r.URL.Query().Get("environment")
+	data, status, err := s.environmentData(project, environment)
+	if err != nil {
+		writeTestError(w, status, err.Error())
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	_ = json.NewEncoder(w).Encode(secretResponseBody{Data: *data})
+}
+
+// handleGraphQL answers secrets queries sent as GET requests, whatever
+// their text, from the project and environment variables.
+func (s *TestServer) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var variables map[string]string
+	if err := json.Unmarshal([]byte(r.URL.Query().Get("variables")), &variables); err != nil {
+		writeTestError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	body := map[string]interface{}{}
+	data, _, err := s.environmentData(variables["project"], variables["environment"])
+	if err != nil {
+		body["data"] = map[string]interface{}{"secrets": nil}
+		body["errors"] = []map[string]string{{"message": err.Error()}}
+	} else {
+		body["data"] = map[string]interface{}{"secrets": data}
+	}
+	w.Header().Set("content-type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// environmentData encrypts the secrets of an environment into the data
+// object of a secrets response.
+func (s *TestServer) environmentData(project, environment string) (*secretResponseBodyData, int, error) {
+	s.mu.Lock()
+	secrets, ok := s.secrets[[2]string{project, environment}]
+	s.mu.Unlock()
+	if !ok {
+		return nil, http.StatusNotFound, errors.New("environment not found")
+	}
+
+	data := &secretResponseBodyData{
+		Project:     secretResponseBodyObject{Title: project},
+		Environment: secretResponseBodyObject{Title: environment},
+	}
+	for key, value := range secrets {
+		encrypted, err := EncryptSecret(TestServerPasscode, key, value)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		data.Secrets = append(data.Secrets, encrypted)
+	}
+	return data, http.StatusOK, nil
+}
+
+// handleUpdateSecrets stores pushed secrets, sent either as key/value
+// objects or encrypted with TestServerPasscode.
+func (s *TestServer) handleUpdateSecrets(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Project     string            `json:"project"`
+		Environment string            `json:"config"`
+		Secrets     []json.RawMessage `json:"secrets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeTestError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updates := map[string]string{}
+	for _, raw := range request.Secrets {
+		var encrypted string
+		if json.Unmarshal(raw, &encrypted) == nil {
+			decrypted, err := decryptSecret(encrypted, TestServerPasscode)
+			if err != nil {
+				writeTestError(w, http.StatusBadRequest, "unable to decrypt secret")
+				return
+			}
+			raw = json.RawMessage(decrypted)
+		}
+		var secret RawSecret
+		if err := json.Unmarshal(raw, &secret); err != nil {
+			writeTestError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		updates[secret.Key] = secret.Value
+	}
+
+	s.mu.Lock()
+	key := [2]string{request.Project, request.Environment}
+	// The map passed to SetSecrets belongs to the caller.
+	for name, value := range s.secrets[key] {
+		if _, ok := updates[name]; !ok {
+			updates[name] = value
+		}
+	}
+	s.secrets[key] = updates
+	s.mu.Unlock()
+	w.Header().Set("content-type", "application/json")
+	_, _ = w.Write([]byte(`{"data":{}}`))
+}
+
+func writeTestError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(apiErrorResponse{Messages: []string{message}})
+}
@@ -0,0 +1,82 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SetResponseHeaderTimeout limits how long the client waits for the headers
+// of a response once the request is written. It does not cover the body;
+// see BodyReadTimeout.
+func (c *OnboardbaseClient) SetResponseHeaderTimeout(timeout time.Duration) error {
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("cannot configure response header timeout of transport %T", c.httpClient.Transport)
+	}
+	transport.ResponseHeaderTimeout = timeout
+	return nil
+}
+
+// maxResponseBodySize bounds the memory a response body may take, so that a
+// faulty or compromised server cannot exhaust it.
+const maxResponseBodySize = 32 << 20
+
+// readBody reads a response body, calling cancel to abort the request when
+// the body is not read within BodyReadTimeout. Bodies larger than
+// maxResponseBodySize are rejected.
+func (c *OnboardbaseClient) readBody(body io.Reader, cancel context.CancelFunc) ([]byte, error) {
+	if c.BodyReadTimeout <= 0 {
+		return readLimited(body)
+	}
+
+	done := make(chan struct{})
+	expired := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-done:
+		case <-c.Clock.After(c.BodyReadTimeout):
+			close(expired)
+			cancel()
+		}
+	}()
+
+	data, err := readLimited(body)
+	if err != nil {
+		select {
+		case <-expired:
+			return nil, fmt.Errorf("response body not read within %s: %w", c.BodyReadTimeout, err)
+		default:
+		}
+	}
+	return data, err
+}
+
+// readLimited reads body up to maxResponseBodySize bytes.
+func readLimited(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxResponseBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxResponseBodySize {
+		return nil, fmt.Errorf("response body exceeds %d bytes", maxResponseBodySize)
+	}
+	return data, nil
+}
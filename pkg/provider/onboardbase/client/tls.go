@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// CipherSuiteIDs resolves cipher suite names, e.g.
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", to their IDs. Names unknown to
+// crypto/tls and suites it considers insecure are rejected.
+func CipherSuiteIDs(names []string) ([]uint16, error) {
+	secure := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+	insecure := map[string]bool{}
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		switch {
+		case ok:
+			ids = append(ids, id)
+		case insecure[name]:
+			return nil, fmt.Errorf("cipher suite %s is insecure", name)
+		default:
+			return nil, fmt.Errorf("unknown cipher suite %s", name)
+		}
+	}
+	return ids, nil
+}
+
+// SetCipherSuites restricts the cipher suites offered for TLS 1.2
+// connections to the named ones. TLS 1.3 suites are not configurable in Go.
+func (c *OnboardbaseClient) SetCipherSuites(names []string) error {
+	ids, err := CipherSuiteIDs(names)
+	if err != nil {
+		return err
+	}
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("cannot configure cipher suites of transport %T", c.httpClient.Transport)
+	}
+	transport.TLSClientConfig.CipherSuites = ids
+	return nil
+}
+
+// SetInsecureSkipVerify disables, or re-enables, verification of the API's
+// certificate.
+func (c *OnboardbaseClient) SetInsecureSkipVerify(skip bool) error {
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("cannot configure certificate verification of transport %T", c.httpClient.Transport)
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = skip //nolint:gosec // only with the store's explicit acknowledgment
+	c.VerifyTLS = !skip
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// traceVerbosity is the log level requests are traced at.
+const traceVerbosity = 2
+
+// traceRequest logs req as an equivalent curl command when TraceRequests is
+// set, so that a failing call can be reproduced by hand. The api_key header
+// is redacted, as are the API key and passcodes wherever else they appear.
+func (c *OnboardbaseClient) traceRequest(req *http.Request, body []byte) {
+	if !c.TraceRequests {
+		return
+	}
+	logger := c.logger().V(traceVerbosity)
+	if !logger.Enabled() {
+		return
+	}
+	logger.Info("tracing request", "operation", req.Method+" "+req.URL.Path, "curl", c.redactCredentials(curlCommand(req, body)))
+}
+
+// curlCommand renders req and its body as a curl command line. The api_key
+// header is always redacted. Compressed bodies are left out.
+func curlCommand(req *http.Request, body []byte) string {
+	args := []string{"curl", "-X", req.Method}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(HeaderAPIKey) {
+				value = redacted
+			}
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+
+	switch {
+	case len(body) == 0:
+	case req.Header.Get(headerContentEncoding) != "":
+		args = append(args, "--data-binary", shellQuote(fmt.Sprintf("[%d encoded bytes]", len(body))))
+	default:
+		args = append(args, "--data-binary", shellQuote(string(body)))
+	}
+	return strings.Join(append(args, shellQuote(req.URL.String())), " ")
+}
+
+// redactCredentials replaces the API key and the passcodes in s.
+func (c *OnboardbaseClient) redactCredentials(s string) string {
+	for _, credential := range append([]string{c.apiKey(), c.passCode()}, c.FallbackPasscodes...) {
+		if credential != "" {
+			s = strings.ReplaceAll(s, credential, redacted)
+		}
+	}
+	return s
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
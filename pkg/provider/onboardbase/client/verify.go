@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrVerifyServerSide is wrapped by the error of VerifyPasscode with
+	// server-side decryption, where secrets are not encrypted.
+	ErrVerifyServerSide = errors.New("the passcode cannot be verified with server-side decryption, secrets are not encrypted")
+	// ErrNothingToVerify is wrapped by the error of VerifyPasscode for an
+	// environment without secrets.
+	ErrNothingToVerify = errors.New("the environment holds no secret")
+)
+
+// VerifyPasscode fetches the environment of request and reports whether its
+// first secret decrypts with the passcode or, while a rotation is under way,
+// with one of FallbackPasscodes. The secret is decrypted into a buffer that
+// is zeroed before returning; no decrypted value is kept or logged. It fails
+// when the environment cannot be fetched or holds no secret.
+func (c *OnboardbaseClient) VerifyPasscode(ctx context.Context, request SecretsRequest) (bool, error) {
+	if c.ServerSideDecryption {
+		return false, &APIError{Err: ErrVerifyServerSide, Message: "unable to verify passcode"}
+	}
+	data, _, err := c.secretsFetcher().fetchSecrets(ctx, c, headers{}, request.buildQueryParams(c.TokenScopedProject))
+	if err != nil {
+		return false, err
+	}
+	if err := c.fetchPayload(ctx, &data.Data); err != nil {
+		return false, err
+	}
+	if len(data.Data.Secrets) == 0 {
+		return false, &APIError{Err: ErrNothingToVerify, Message: "unable to verify passcode"}
+	}
+
+	for _, passcode := range append([]string{c.passCode()}, c.FallbackPasscodes...) {
+		plaintext, err := decryptPayload(passcode, data.Data.Secrets[0])
+		if err != nil {
+			continue
+		}
+		valid := json.Valid(plaintext)
+		zero(plaintext)
+		if valid {
+			return true, nil
+		}
+	}
+	c.logger().V(1).Info("passcode verification failed", "project", request.Project, "environment", request.Environment, "secret", secretID(data.Data.Secrets[0]))
+	return false, nil
+}
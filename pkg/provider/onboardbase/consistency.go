@@ -0,0 +1,79 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+const (
+	// readAfterWriteBase and readAfterWriteMax bound the backoff between
+	// reads while waiting for a pushed value to become visible.
+	readAfterWriteBase = 100 * time.Millisecond
+	readAfterWriteMax  = 2 * time.Second
+
+	errReadAfterWrite = "secret %s was pushed but its new value was not readable within %s"
+)
+
+// readAfterWrite waits, after a push, until reads return the pushed value, so
+// that a GetSecret following PushSecret never sees the previous one.
+type readAfterWrite struct {
+	timeout time.Duration
+	backoff dClient.Backoff
+}
+
+func newReadAfterWrite(timeout time.Duration) *readAfterWrite {
+	return &readAfterWrite{
+		timeout: timeout,
+		backoff: dClient.ExponentialBackoff{Base: readAfterWriteBase, Max: readAfterWriteMax},
+	}
+}
+
+// waitVisible polls the secret request names until its value is value. It
+// gives up after the timeout, reporting the last read error, if any.
+func (w *readAfterWrite) waitVisible(ctx context.Context, onboardbase SecretsClientInterface, request dClient.SecretRequest, value string) error {
+	waitCtx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		response, err := onboardbase.GetSecret(waitCtx, request)
+		if err == nil && response.Value == value {
+			return nil
+		}
+		if err != nil && waitCtx.Err() == nil && !errors.Is(err, dClient.ErrSecretNotFound) {
+			lastErr = err
+		}
+
+		timer := time.NewTimer(w.backoff.Delay(attempt))
+		select {
+		case <-timer.C:
+		case <-waitCtx.Done():
+			timer.Stop()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if lastErr != nil {
+				return fmt.Errorf(errReadAfterWrite+": %w", request.Name, w.timeout, lastErr)
+			}
+			return fmt.Errorf(errReadAfterWrite, request.Name, w.timeout)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+// assembleDockerConfigJSON builds a .dockerconfigjson, for image pull
+// secrets, from the secrets PREFIX_REGISTRY, PREFIX_USERNAME and
+// PREFIX_PASSWORD, and optionally PREFIX_EMAIL, where PREFIX is the remote
+// key.
+const assembleDockerConfigJSON = "dockerconfigjson"
+
+const (
+	dockerRegistrySuffix = "_REGISTRY"
+	dockerUsernameSuffix = "_USERNAME"
+	dockerPasswordSuffix = "_PASSWORD"
+	dockerEmailSuffix    = "_EMAIL"
+
+	errMissingDockerConfigKeys = "cannot build docker config %s: missing %s"
+)
+
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+type dockerConfigAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email,omitempty"`
+	Auth     string `json:"auth"`
+}
+
+// getDockerConfigJSON assembles a .dockerconfigjson from the registry
+// credentials stored under the name of request as prefix.
+func (c *Client) getDockerConfigJSON(ctx context.Context, request dClient.SecretRequest) ([]byte, error) {
+	prefix := request.Name
+	values := map[string]string{}
+	var missing []string
+	for _, suffix := range []string{dockerRegistrySuffix, dockerUsernameSuffix, dockerPasswordSuffix, dockerEmailSuffix} {
+		request.Name = prefix + suffix
+		value, err := c.getSecretValue(ctx, request)
+		if err != nil && !errors.Is(err, dClient.ErrSecretNotFound) {
+			return nil, err
+		}
+		if c.trimSpace {
+			value = bytes.TrimSpace(value)
+		}
+		if len(value) == 0 && suffix != dockerEmailSuffix {
+			missing = append(missing, request.Name)
+		}
+		values[suffix] = string(value)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf(errMissingDockerConfigKeys, prefix, strings.Join(missing, ", "))
+	}
+
+	username, password := values[dockerUsernameSuffix], values[dockerPasswordSuffix]
+	config := dockerConfigJSON{Auths: map[string]dockerConfigAuth{
+		values[dockerRegistrySuffix]: {
+			Username: username,
+			Password: password,
+			Email:    values[dockerEmailSuffix],
+			Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+		},
+	}}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf(errMarshalSecrets, err)
+	}
+	return data, nil
+}
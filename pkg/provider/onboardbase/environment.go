@@ -0,0 +1,57 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	errGetNamespace                 = "unable to get namespace %s to resolve the environment: %w"
+	errMissingEnvironmentLabel      = "namespace %s has no label %s naming the environment"
+	errMissingEnvironmentAnnotation = "namespace %s has no annotation %s naming the environment"
+)
+
+// resolveEnvironment returns the environment to read secrets from: the value
+// of the configured label or annotation of the client's namespace, or the
+// store's environment. The store's environment is also used without a
+// namespace, as when a ClusterSecretStore is validated.
+func (c *Client) resolveEnvironment(ctx context.Context) (string, error) {
+	from := c.store.EnvironmentFromNamespace
+	if from == nil || c.namespace == "" {
+		return c.store.Environment, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := c.kube.Get(ctx, types.NamespacedName{Name: c.namespace}, namespace); err != nil {
+		return "", fmt.Errorf(errGetNamespace, c.namespace, err)
+	}
+	if from.Label != "" {
+		environment := namespace.Labels[from.Label]
+		if environment == "" {
+			return "", fmt.Errorf(errMissingEnvironmentLabel, c.namespace, from.Label)
+		}
+		return environment, nil
+	}
+	environment := namespace.Annotations[from.Annotation]
+	if environment == "" {
+		return "", fmt.Errorf(errMissingEnvironmentAnnotation, c.namespace, from.Annotation)
+	}
+	return environment, nil
+}
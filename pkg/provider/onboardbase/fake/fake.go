@@ -1,8 +1,10 @@
 package fake
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -10,24 +12,108 @@ import (
 )
 
 type OnboardbaseClient struct {
-	getSecret func(request client.SecretRequest) (*client.SecretResponse, error)
+	getSecret    func(request client.SecretRequest) (*client.SecretResponse, error)
+	getSecrets   func(request client.SecretsRequest) (*client.SecretsResponse, error)
+	authenticate func() error
+	verify       func() (bool, error)
+	updates      []client.UpdateSecretsRequest
+	fetches      int
 }
 
 func (obbc *OnboardbaseClient) BaseURL() *url.URL {
 	return &url.URL{Scheme: "https", Host: "public.onboardbase.com"}
 }
 
-func (obbc *OnboardbaseClient) Authenticate() error {
-	return nil
+func (obbc *OnboardbaseClient) Authenticate(_ context.Context) error {
+	if obbc.authenticate == nil {
+		return nil
+	}
+	return obbc.authenticate()
 }
 
-func (obbc *OnboardbaseClient) GetSecret(request client.SecretRequest) (*client.SecretResponse, error) {
+func (obbc *OnboardbaseClient) GetSecret(_ context.Context, request client.SecretRequest) (*client.SecretResponse, error) {
 	return obbc.getSecret(request)
 }
 
-func (obbc *OnboardbaseClient) GetSecrets(request client.SecretsRequest) (*client.SecretsResponse, error) {
-	// Not implemented
-	return &client.SecretsResponse{}, nil
+func (obbc *OnboardbaseClient) GetSecrets(_ context.Context, request client.SecretsRequest) (*client.SecretsResponse, error) {
+	if obbc.getSecrets == nil {
+		return &client.SecretsResponse{}, nil
+	}
+	return obbc.getSecrets(request)
+}
+
+// GetSecretsByNames answers from GetSecrets, so WithSecrets and
+// WithSecretsFunc configure both.
+func (obbc *OnboardbaseClient) GetSecretsByNames(ctx context.Context, request client.SecretsRequest, names []string) (map[string]*client.SecretResponse, error) {
+	obbc.fetches++
+	response, err := obbc.GetSecrets(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		for name := range response.Secrets {
+			names = append(names, name)
+		}
+	}
+	secrets := map[string]*client.SecretResponse{}
+	for _, name := range names {
+		if value, ok := response.Secrets[name]; ok {
+			secrets[name] = &client.SecretResponse{Name: name, Value: value}
+		}
+	}
+	return secrets, nil
+}
+
+// Fetches returns how many times GetSecretsByNames was called.
+func (obbc *OnboardbaseClient) Fetches() int {
+	return obbc.fetches
+}
+
+func (obbc *OnboardbaseClient) LastSuccessfulSync(_, _ string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (obbc *OnboardbaseClient) UpdateSecrets(_ context.Context, request client.UpdateSecretsRequest) error {
+	obbc.updates = append(obbc.updates, request)
+	return nil
+}
+
+// Updates returns the requests passed to UpdateSecrets.
+func (obbc *OnboardbaseClient) Updates() []client.UpdateSecretsRequest {
+	return obbc.updates
+}
+
+func (obbc *OnboardbaseClient) DeleteSecrets(_ context.Context, requests []client.SecretRequest) (*client.DeleteSecretsResponse, error) {
+	response := &client.DeleteSecretsResponse{}
+	for _, request := range requests {
+		response.Results = append(response.Results, client.DeleteSecretResult{Request: request})
+	}
+	return response, nil
+}
+
+func (obbc *OnboardbaseClient) Diagnose(_ context.Context, project, environment string) *client.DiagnosticReport {
+	return &client.DiagnosticReport{Project: project, Environment: environment}
+}
+
+func (obbc *OnboardbaseClient) VerifyPasscode(_ context.Context, _ client.SecretsRequest) (bool, error) {
+	if obbc.verify == nil {
+		return true, nil
+	}
+	return obbc.verify()
+}
+
+func (obbc *OnboardbaseClient) Status(_ context.Context, _, _ string) client.StoreStatus {
+	return client.StoreStatus{Reachable: true, Authenticated: true, ReadCircuit: client.CircuitClosed, WriteCircuit: client.CircuitClosed}
+}
+
+// WithAuthenticate makes Authenticate call fn.
+func (obbc *OnboardbaseClient) WithAuthenticate(fn func() error) {
+	obbc.authenticate = fn
+}
+
+// WithVerifyPasscode makes VerifyPasscode call fn.
+func (obbc *OnboardbaseClient) WithVerifyPasscode(fn func() (bool, error)) {
+	obbc.verify = fn
 }
 
 func (obbc *OnboardbaseClient) WithValue(request client.SecretRequest, response *client.SecretResponse, err error) {
@@ -40,3 +126,24 @@ func (obbc *OnboardbaseClient) WithValue(request client.SecretRequest, response
 		}
 	}
 }
+
+func (obbc *OnboardbaseClient) WithSecrets(request client.SecretsRequest, response *client.SecretsResponse, err error) {
+	if obbc != nil {
+		obbc.getSecrets = func(requestIn client.SecretsRequest) (*client.SecretsResponse, error) {
+			if !cmp.Equal(requestIn, request) {
+				return nil, fmt.Errorf("unexpected test argument")
+			}
+			return response, err
+		}
+	}
+}
+
+// WithSecretsFunc makes GetSecrets call fn, to answer several requests.
+func (obbc *OnboardbaseClient) WithSecretsFunc(fn func(request client.SecretsRequest) (*client.SecretsResponse, error)) {
+	obbc.getSecrets = fn
+}
+
+// WithValueFunc makes GetSecret call fn, to answer several requests.
+func (obbc *OnboardbaseClient) WithValueFunc(fn func(request client.SecretRequest) (*client.SecretResponse, error)) {
+	obbc.getSecret = fn
+}
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"fmt"
+
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+const (
+	// environmentPrefixTag makes GetAllSecrets prefix each key with the
+	// environment its value comes from, e.g. "dev/API_KEY", which tells the
+	// keys of the parentEnvironment from the environment's own.
+	environmentPrefixTag = "prefixEnvironment"
+
+	errInheritanceCycle  = "environment %q cannot inherit from itself: parentEnvironment must name another environment"
+	errTagsNotCombinable = "the %s tag cannot be combined with the %s tag"
+)
+
+// checkInheritance fails when environment would inherit from itself, which
+// can happen once the environment is read from the namespace.
+func checkInheritance(environment, parent string) error {
+	if parent != "" && parent == environment {
+		return fmt.Errorf(errInheritanceCycle, environment)
+	}
+	return nil
+}
+
+// environmentKey returns key, prefixed with environment when prefix is set.
+func environmentKey(environment, key string, prefix bool) string {
+	if !prefix {
+		return key
+	}
+	return environment + dClient.EnvironmentKeySeparator + key
+}
+
+// getInheritedSecrets returns the secrets of the client's environment merged
+// over those of its parent environment: keys of the child override the
+// parent's. With prefix, each key is prefixed with the environment its value
+// comes from.
+func (c *Client) getInheritedSecrets(ctx context.Context, prefix bool) (map[string][]byte, error) {
+	parent, err := c.getEnvironmentSecrets(ctx, dClient.SecretsRequest{
+		Project:     c.project,
+		Environment: c.parentEnvironment,
+	})
+	if err != nil {
+		return nil, err
+	}
+	child, err := c.getEnvironmentSecrets(ctx, dClient.SecretsRequest{
+		Project:     c.project,
+		Environment: c.environment,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string][]byte, len(parent)+len(child))
+	for key, value := range parent {
+		if _, overridden := child[key]; !overridden {
+			merged[environmentKey(c.parentEnvironment, key, prefix)] = value
+		}
+	}
+	for key, value := range child {
+		merged[environmentKey(c.environment, key, prefix)] = value
+	}
+	return merged, nil
+}
@@ -0,0 +1,97 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	errInvalidKeyPattern = "invalid key pattern %q: %w"
+	// errRawPayloadsFiltered is returned rather than serving raw payloads,
+	// which have no key to filter on, past allowedKeys, deniedKeys or
+	// keyPrefix.
+	errRawPayloadsFiltered = "rawPayloads cannot be combined with allowedKeys, deniedKeys or keyPrefix, raw payloads have no key to filter on"
+)
+
+// keyFilter restricts the keys a store exposes. A key is exposed when it
+// matches none of the denied patterns and, if any are set, one of the allowed
+// patterns. Patterns use path.Match syntax.
+//
+// With a prefix, the store only sees the remote keys starting with it, by
+// their name without the prefix; patterns match that name.
+type keyFilter struct {
+	allowed []string
+	denied  []string
+	prefix  string
+}
+
+// remoteKey returns the remote name of the key the store exposes as key.
+func (f keyFilter) remoteKey(key string) string {
+	return f.prefix + key
+}
+
+func (f keyFilter) allows(key string) bool {
+	if matchesAny(f.denied, key) {
+		return false
+	}
+	return len(f.allowed) == 0 || matchesAny(f.allowed, key)
+}
+
+// restricts reports whether f hides any key.
+func (f keyFilter) restricts() bool {
+	return len(f.allowed) > 0 || len(f.denied) > 0 || f.prefix != ""
+}
+
+// filter returns the secrets, keyed by remote name, that f allows, keyed by
+// the name the store exposes them as.
+func (f keyFilter) filter(secrets map[string][]byte) map[string][]byte {
+	if !f.restricts() {
+		return secrets
+	}
+	filtered := make(map[string][]byte, len(secrets))
+	for key, value := range secrets {
+		if !strings.HasPrefix(key, f.prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, f.prefix)
+		if f.allows(name) {
+			filtered[name] = value
+		}
+	}
+	return filtered
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		// Patterns are validated with the store, so errors are not expected.
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validateKeyPatterns checks that patterns are well-formed.
+func validateKeyPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf(errInvalidKeyPattern, pattern, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"encoding/json"
+	"fmt"
+
+	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
+)
+
+const (
+	errMergeRemoteNotJSON = "remote value of %s is not a JSON object, cannot merge: %w"
+	errMergeLocalNotJSON  = "pushed value of %s is not a JSON object, cannot merge: %w"
+)
+
+// mergeJSON deep-merges the local JSON object into the remote one. Nested
+// objects are merged recursively; any other conflicting field is resolved
+// according to strategy. Numbers are written back as they were read.
+func mergeJSON(key string, remote, local []byte, strategy esv1beta1.OnboardbasePushMergeStrategy) ([]byte, error) {
+	var remoteObj, localObj map[string]interface{}
+	if err := decodeJSON(remote, &remoteObj); err != nil {
+		return nil, fmt.Errorf(errMergeRemoteNotJSON, key, err)
+	}
+	if err := decodeJSON(local, &localObj); err != nil {
+		return nil, fmt.Errorf(errMergeLocalNotJSON, key, err)
+	}
+	return json.Marshal(mergeObjects(remoteObj, localObj, strategy == esv1beta1.OnboardbasePushMergeLocalWins))
+}
+
+func mergeObjects(remote, local map[string]interface{}, localWins bool) map[string]interface{} {
+	if remote == nil {
+		remote = map[string]interface{}{}
+	}
+	for key, localValue := range local {
+		remoteValue, exists := remote[key]
+		if !exists {
+			remote[key] = localValue
+			continue
+		}
+		remoteChild, remoteIsObj := remoteValue.(map[string]interface{})
+		localChild, localIsObj := localValue.(map[string]interface{})
+		switch {
+		case remoteIsObj && localIsObj:
+			remote[key] = mergeObjects(remoteChild, localChild, localWins)
+		case localWins:
+			remote[key] = localValue
+		}
+	}
+	return remote
+}
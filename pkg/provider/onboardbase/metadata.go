@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+const (
+	// metadataUpdatedAt is the RFC 3339 time the secret was last changed.
+	metadataUpdatedAt = "updatedAt"
+	// metadataProject and metadataEnvironment name the project and
+	// environment, or config, the secret was read from.
+	metadataProject     = "project"
+	metadataEnvironment = "environment"
+
+	errMetadataNotFound = "metadata %s not found for secret %s"
+)
+
+// getSecretMetadata returns the metadata of a secret as a JSON object, or the
+// single field named by property, including the project and environment the
+// secret was read from. Fields the API does not report are left out. A template targeting Annotations can copy them onto the Secret.
+func (c *Client) getSecretMetadata(ctx context.Context, request dClient.SecretRequest, property string) ([]byte, error) {
+	if !c.keys.allows(request.Name) {
+		return nil, dClient.ErrSecretNotFound
+	}
+	secret, err := c.fetchSecret(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		metadataProject:     request.Project,
+		metadataEnvironment: request.Environment,
+	}
+	if secret.UpdatedAt != nil {
+		metadata[metadataUpdatedAt] = secret.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+
+	if property == "" {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, fmt.Errorf(errMarshalSecrets, err)
+		}
+		return data, nil
+	}
+	value, ok := metadata[property]
+	if !ok {
+		return nil, fmt.Errorf(errMetadataNotFound, property, request.Name)
+	}
+	return []byte(value), nil
+}
@@ -0,0 +1,1658 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kclient "sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/external-secrets/external-secrets/apis/externalsecrets/v1alpha1"
+	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
+	v1 "github.com/external-secrets/external-secrets/apis/meta/v1"
+	"github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+	"github.com/external-secrets/external-secrets/pkg/provider/onboardbase/fake"
+	"github.com/external-secrets/external-secrets/pkg/utils"
+)
+
+const (
+	validSecretName  = "API_KEY"
+	validSecretValue = "3a3ea4f5"
+	missingSecret    = "INVALID_NAME"
+	missingSecretErr = "could not get secret"
+)
+
+type onboardbaseTestCase struct {
+	label          string
+	fakeClient     *fake.OnboardbaseClient
+	request        client.SecretRequest
+	response       *client.SecretResponse
+	remoteRef      *esv1beta1.ExternalSecretDataRemoteRef
+	apiErr         error
+	expectError    string
+	expectedSecret string
+	expectedData   map[string][]byte
+	trimSpace      bool
+}
+
+func makeValidAPIRequest() client.SecretRequest {
+	return client.SecretRequest{
+		Name: validSecretName,
+	}
+}
+
+func makeValidAPIOutput() *client.SecretResponse {
+	return &client.SecretResponse{
+		Name:  validSecretName,
+		Value: validSecretValue,
+	}
+}
+
+func makeValidRemoteRef() *esv1beta1.ExternalSecretDataRemoteRef {
+	return &esv1beta1.ExternalSecretDataRemoteRef{
+		Key: validSecretName,
+	}
+}
+
+func makeValidOnboardbaseTestCase() *onboardbaseTestCase {
+	return &onboardbaseTestCase{
+		fakeClient:     &fake.OnboardbaseClient{},
+		request:        makeValidAPIRequest(),
+		response:       makeValidAPIOutput(),
+		remoteRef:      makeValidRemoteRef(),
+		apiErr:         nil,
+		expectError:    "",
+		expectedSecret: validSecretValue,
+		expectedData:   make(map[string][]byte),
+	}
+}
+
+func makeValidOnboardbaseTestCaseCustom(tweaks ...func(pstc *onboardbaseTestCase)) *onboardbaseTestCase {
+	pstc := makeValidOnboardbaseTestCase()
+	for _, fn := range tweaks {
+		fn(pstc)
+	}
+	pstc.fakeClient.WithValue(pstc.request, pstc.response, pstc.apiErr)
+	return pstc
+}
+
+func TestGetSecret(t *testing.T) {
+	setSecret := func(pstc *onboardbaseTestCase) {
+		pstc.label = "set secret"
+	}
+
+	setMissingSecret := func(pstc *onboardbaseTestCase) {
+		pstc.label = "invalid missing secret"
+		pstc.remoteRef.Key = missingSecret
+		pstc.request.Name = missingSecret
+		pstc.response = nil
+		pstc.expectError = missingSecretErr
+		pstc.apiErr = fmt.Errorf("")
+	}
+
+	setUntrimmedSecret := func(pstc *onboardbaseTestCase) {
+		pstc.label = "whitespace preserved by default"
+		pstc.response.Value = " token\n"
+		pstc.expectedSecret = " token\n"
+	}
+
+	setTrimmedSecret := func(pstc *onboardbaseTestCase) {
+		pstc.label = "whitespace trimmed"
+		pstc.response.Value = " token\n"
+		pstc.expectedSecret = "token"
+		pstc.trimSpace = true
+	}
+
+	setDefaultForMissingSecret := func(pstc *onboardbaseTestCase) {
+		pstc.label = "default for missing secret"
+		pstc.remoteRef.Key = missingSecret + "?default=fallback"
+		pstc.request.Name = missingSecret
+		pstc.response = nil
+		pstc.apiErr = fmt.Errorf("%w", client.ErrSecretNotFound)
+		pstc.expectedSecret = "fallback"
+	}
+
+	setDefaultIgnoredForExistingSecret := func(pstc *onboardbaseTestCase) {
+		pstc.label = "default ignored for existing secret"
+		pstc.remoteRef.Key = validSecretName + "?default=fallback"
+	}
+
+	setDefaultWithAPIError := func(pstc *onboardbaseTestCase) {
+		pstc.label = "default does not hide API errors"
+		pstc.remoteRef.Key = validSecretName + "?default=fallback"
+		pstc.response = nil
+		pstc.apiErr = fmt.Errorf("unable to load response")
+		pstc.expectError = missingSecretErr
+	}
+
+	setRequestHeaders := func(pstc *onboardbaseTestCase) {
+		pstc.label = "request headers from remote key"
+		pstc.remoteRef.Key = validSecretName + "?header.X-Gateway-Policy=strict"
+		pstc.request.Headers = map[string]string{"X-Gateway-Policy": "strict"}
+	}
+
+	setReservedRequestHeader := func(pstc *onboardbaseTestCase) {
+		pstc.label = "api_key header cannot be overridden"
+		pstc.remoteRef.Key = validSecretName + "?header.api_key=other"
+		pstc.expectError = "header api_key cannot be overridden"
+	}
+
+	setYAMLProperty := func(pstc *onboardbaseTestCase) {
+		pstc.label = "property of yaml value"
+		pstc.remoteRef.Key = validSecretName + "?format=yaml"
+		pstc.remoteRef.Property = "database.password"
+		pstc.response.Value = "database:\n  password: s3cr3t\n"
+		pstc.expectedSecret = "s3cr3t"
+	}
+
+	setCoordinates := func(pstc *onboardbaseTestCase) {
+		pstc.label = "project and environment from remote key"
+		pstc.remoteRef.Key = "billing:staging:" + validSecretName
+		pstc.request.Project = "billing"
+		pstc.request.Environment = "staging"
+	}
+
+	setColonInName := func(pstc *onboardbaseTestCase) {
+		pstc.label = "name with a colon taken literally"
+		pstc.remoteRef.Key = "billing:" + validSecretName
+		pstc.request.Name = "billing:" + validSecretName
+	}
+
+	setEmptyCoordinates := func(pstc *onboardbaseTestCase) {
+		pstc.label = "name with empty coordinates taken literally"
+		pstc.remoteRef.Key = "billing::" + validSecretName
+		pstc.request.Name = "billing::" + validSecretName
+	}
+
+	setTransforms := func(pstc *onboardbaseTestCase) {
+		pstc.label = "transforms applied in order"
+		pstc.remoteRef.Key = validSecretName + "?transform=trim,base64decode&transform=property:db.password"
+		pstc.response.Value = " eyJkYiI6IHsicGFzc3dvcmQiOiAiczNjcjN0In19\n"
+		pstc.expectedSecret = "s3cr3t"
+	}
+
+	setFailedTransform := func(pstc *onboardbaseTestCase) {
+		pstc.label = "failed transform step"
+		pstc.remoteRef.Key = validSecretName + "?transform=trim,base64decode"
+		pstc.response.Value = "not base64!"
+		pstc.expectError = "transform 2 (base64decode) of secret API_KEY failed"
+	}
+
+	setUnknownTransform := func(pstc *onboardbaseTestCase) {
+		pstc.label = "unknown transform"
+		pstc.remoteRef.Key = validSecretName + "?transform=rot13"
+		pstc.expectError = `unknown transform "rot13"`
+	}
+
+	setPropertyTransformWithoutPath := func(pstc *onboardbaseTestCase) {
+		pstc.label = "property transform without a path"
+		pstc.remoteRef.Key = validSecretName + "?transform=property"
+		pstc.expectError = "transform property requires an argument"
+	}
+
+	testCases := []*onboardbaseTestCase{
+		makeValidOnboardbaseTestCaseCustom(setSecret),
+		makeValidOnboardbaseTestCaseCustom(setMissingSecret),
+		makeValidOnboardbaseTestCaseCustom(setUntrimmedSecret),
+		makeValidOnboardbaseTestCaseCustom(setTrimmedSecret),
+		makeValidOnboardbaseTestCaseCustom(setDefaultForMissingSecret),
+		makeValidOnboardbaseTestCaseCustom(setDefaultIgnoredForExistingSecret),
+		makeValidOnboardbaseTestCaseCustom(setDefaultWithAPIError),
+		makeValidOnboardbaseTestCaseCustom(setRequestHeaders),
+		makeValidOnboardbaseTestCaseCustom(setReservedRequestHeader),
+		makeValidOnboardbaseTestCaseCustom(setYAMLProperty),
+		makeValidOnboardbaseTestCaseCustom(setCoordinates),
+		makeValidOnboardbaseTestCaseCustom(setColonInName),
+		makeValidOnboardbaseTestCaseCustom(setEmptyCoordinates),
+		makeValidOnboardbaseTestCaseCustom(setTransforms),
+		makeValidOnboardbaseTestCaseCustom(setFailedTransform),
+		makeValidOnboardbaseTestCaseCustom(setUnknownTransform),
+		makeValidOnboardbaseTestCaseCustom(setPropertyTransformWithoutPath),
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := Client{onboardbase: tc.fakeClient, trimSpace: tc.trimSpace}
+			out, err := c.GetSecret(context.Background(), *tc.remoteRef)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && !cmp.Equal(string(out), tc.expectedSecret) {
+				t.Errorf("unexpected secret data: expected %#v, got %#v", tc.expectedSecret, string(out))
+			}
+		})
+	}
+}
+
+func TestGetSecretMap(t *testing.T) {
+	simpleJSON := func(pstc *onboardbaseTestCase) {
+		pstc.label = "valid unmarshalling"
+		pstc.response.Value = `{"API_KEY":"3a3ea4f5"}`
+		pstc.expectedData["API_KEY"] = []byte("3a3ea4f5")
+	}
+
+	complexJSON := func(pstc *onboardbaseTestCase) {
+		pstc.label = "valid unmarshalling for nested json"
+		pstc.response.Value = `{"API_KEY": "3a3ea4f5", "AUTH_SA": {"appID": "a1ea-48bd-8749-b6f5ec3c5a1f"}}`
+		pstc.expectedData["API_KEY"] = []byte("3a3ea4f5")
+		pstc.expectedData["AUTH_SA"] = []byte(`{"appID": "a1ea-48bd-8749-b6f5ec3c5a1f"}`)
+	}
+
+	trimmedJSON := func(pstc *onboardbaseTestCase) {
+		pstc.label = "values trimmed"
+		pstc.response.Value = `{"API_KEY": "3a3ea4f5\n"}`
+		pstc.expectedData["API_KEY"] = []byte("3a3ea4f5")
+		pstc.trimSpace = true
+	}
+
+	setInvalidJSON := func(pstc *onboardbaseTestCase) {
+		pstc.label = "invalid json"
+		pstc.response.Value = `{"API_KEY": "3a3ea4f`
+		pstc.expectError = "unable to unmarshal secret"
+	}
+
+	setAPIError := func(pstc *onboardbaseTestCase) {
+		pstc.label = "client error"
+		pstc.response = &client.SecretResponse{}
+		pstc.expectError = missingSecretErr
+		pstc.apiErr = fmt.Errorf("")
+	}
+
+	setYAML := func(pstc *onboardbaseTestCase) {
+		pstc.label = "yaml format"
+		pstc.remoteRef.Key = validSecretName + "?format=yaml"
+		pstc.response.Value = "API_KEY: 3a3ea4f5\nAUTH_SA:\n  appID: a1ea\n"
+		pstc.expectedData["API_KEY"] = []byte("3a3ea4f5")
+		pstc.expectedData["AUTH_SA"] = []byte(`{"appID":"a1ea"}`)
+	}
+
+	setInvalidYAML := func(pstc *onboardbaseTestCase) {
+		pstc.label = "invalid yaml"
+		pstc.remoteRef.Key = validSecretName + "?format=yaml"
+		pstc.response.Value = "API_KEY: [3a3ea4f5"
+		pstc.expectError = "unable to parse secret API_KEY as YAML"
+	}
+
+	setUnknownFormat := func(pstc *onboardbaseTestCase) {
+		pstc.label = "unknown format"
+		pstc.remoteRef.Key = validSecretName + "?format=toml"
+		pstc.expectError = `unknown format "toml", expected json or yaml`
+	}
+
+	testCases := []*onboardbaseTestCase{
+		makeValidOnboardbaseTestCaseCustom(simpleJSON),
+		makeValidOnboardbaseTestCaseCustom(complexJSON),
+		makeValidOnboardbaseTestCaseCustom(trimmedJSON),
+		makeValidOnboardbaseTestCaseCustom(setInvalidJSON),
+		makeValidOnboardbaseTestCaseCustom(setAPIError),
+		makeValidOnboardbaseTestCaseCustom(setYAML),
+		makeValidOnboardbaseTestCaseCustom(setInvalidYAML),
+		makeValidOnboardbaseTestCaseCustom(setUnknownFormat),
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := Client{onboardbase: tc.fakeClient, trimSpace: tc.trimSpace}
+			out, err := c.GetSecretMap(context.Background(), *tc.remoteRef)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && !cmp.Equal(out, tc.expectedData) {
+				t.Errorf("unexpected secret data: expected %#v, got %#v", tc.expectedData, out)
+			}
+		})
+	}
+}
+
+func ErrorContains(out error, want string) bool {
+	if out == nil {
+		return want == ""
+	}
+	if want == "" {
+		return false
+	}
+	return strings.Contains(out.Error(), want)
+}
+
+func TestGetSecretAllAsJSON(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"B": "2", "A": "1"},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+
+	for _, key := range []string{"", allSecretsKey} {
+		out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: key})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := `{"A":"1","B":"2"}`; string(out) != want {
+			t.Errorf("unexpected secret data: expected %s, got %s", want, out)
+		}
+	}
+}
+
+func TestGetSecretAllAsJSONWithCoordinates(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "billing", Environment: "staging"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"A": "1"},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+
+	out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "billing:staging:" + allSecretsKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"A":"1"}`; string(out) != want {
+		t.Errorf("unexpected secret data: expected %s, got %s", want, out)
+	}
+}
+
+func TestGetSecretCacheFallback(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newSecretCache(clientfake.NewClientBuilder().Build(), "default", "onboardbase-cache", time.Hour)
+	cache.now = func() time.Time { return now }
+
+	fakeClient := &fake.OnboardbaseClient{}
+	request := client.SecretRequest{Project: "app", Environment: "dev", Name: validSecretName}
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev", cache: cache}
+	ref := esv1beta1.ExternalSecretDataRemoteRef{Key: validSecretName}
+
+	fakeClient.WithValue(request, &client.SecretResponse{Name: validSecretName, Value: validSecretValue}, nil)
+	if _, err := c.GetSecret(context.Background(), ref); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fakeClient.WithValue(request, nil, &client.APIError{Message: "unavailable", StatusCode: 503})
+	now = now.Add(30 * time.Minute)
+	out, err := c.GetSecret(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("expected the cached value, got %v", err)
+	}
+	if string(out) != validSecretValue {
+		t.Errorf("unexpected secret data: expected %q, got %q", validSecretValue, out)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := c.GetSecret(context.Background(), ref); !ErrorContains(err, "is stale") {
+		t.Errorf("expected a stale cache error, got %v", err)
+	}
+
+	fakeClient.WithValue(request, nil, fmt.Errorf("%w", client.ErrSecretNotFound))
+	now = now.Add(-time.Hour)
+	if _, err := c.GetSecret(context.Background(), ref); err == nil {
+		t.Errorf("expected a missing secret not to be served from cache")
+	}
+}
+
+// conflictingKube fails the first conflicts updates with a conflict and
+// counts the others.
+type conflictingKube struct {
+	kclient.Client
+	conflicts int
+	updates   int
+}
+
+func (k *conflictingKube) Update(ctx context.Context, obj kclient.Object, opts ...kclient.UpdateOption) error {
+	if k.conflicts > 0 {
+		k.conflicts--
+		return apierrors.NewConflict(corev1.Resource("secrets"), obj.GetName(), errors.New("modified concurrently"))
+	}
+	k.updates++
+	return k.Client.Update(ctx, obj, opts...)
+}
+
+func TestSecretCachePut(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	kube := &conflictingKube{Client: clientfake.NewClientBuilder().Build()}
+	cache := newSecretCache(kube, "default", "onboardbase-cache", time.Hour)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if err := cache.put(ctx, "app/dev/A", []byte("1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.put(ctx, "app/dev/A", []byte("1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kube.updates != 0 {
+		t.Errorf("expected an unchanged value not to be written, got %d updates", kube.updates)
+	}
+
+	kube.conflicts = 1
+	if err := cache.put(ctx, "app/dev/A", []byte("2")); err != nil {
+		t.Fatalf("expected the conflict to be retried, got %v", err)
+	}
+	if kube.updates != 1 {
+		t.Errorf("expected a changed value to be written once, got %d updates", kube.updates)
+	}
+
+	now = now.Add(31 * time.Minute)
+	if err := cache.put(ctx, "app/dev/A", []byte("2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kube.updates != 2 {
+		t.Errorf("expected an unchanged value to be refreshed halfway to maxStaleness, got %d updates", kube.updates)
+	}
+	if value, err := cache.get(ctx, "app/dev/A"); err != nil || string(value) != "2" {
+		t.Errorf("unexpected cached value: %q, %v", value, err)
+	}
+}
+
+func TestGetSecretMetadata(t *testing.T) {
+	updatedAt := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	request := client.SecretRequest{Project: "app", Environment: "dev", Name: validSecretName}
+	testCases := []struct {
+		label       string
+		response    *client.SecretResponse
+		property    string
+		expected    string
+		expectError string
+	}{
+		{label: "all metadata", response: &client.SecretResponse{Value: validSecretValue, UpdatedAt: &updatedAt}, expected: `{"environment":"dev","project":"app","updatedAt":"2023-03-01T12:00:00Z"}`},
+		{label: "single field", response: &client.SecretResponse{Value: validSecretValue, UpdatedAt: &updatedAt}, property: "updatedAt", expected: "2023-03-01T12:00:00Z"},
+		{label: "missing timestamp omitted", response: &client.SecretResponse{Value: validSecretValue}, expected: `{"environment":"dev","project":"app"}`},
+		{label: "source environment", response: &client.SecretResponse{Value: validSecretValue}, property: "environment", expected: "dev"},
+		{label: "missing timestamp field", response: &client.SecretResponse{Value: validSecretValue}, property: "updatedAt", expectError: "metadata updatedAt not found for secret API_KEY"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			fakeClient := &fake.OnboardbaseClient{}
+			fakeClient.WithValue(request, tc.response, nil)
+			c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+			out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{
+				Key:            validSecretName,
+				Property:       tc.property,
+				MetadataPolicy: esv1beta1.ExternalSecretMetadataPolicyFetch,
+			})
+			if !ErrorContains(err, tc.expectError) {
+				t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && string(out) != tc.expected {
+				t.Errorf("unexpected metadata: expected %s, got %s", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestGetSecretSnapshots(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"API": "key", "DB": "db"},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev", snapshots: newSecretSnapshots()}
+
+	for key, expected := range map[string]string{"API": "key", "DB": "db"} {
+		out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: key})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(out) != expected {
+			t.Errorf("unexpected value for %s: expected %s, got %s", key, expected, out)
+		}
+	}
+	if _, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "MISSING?default=none"}); err != nil {
+		t.Errorf("expected the default for a missing secret, got %v", err)
+	}
+	if fetches := fakeClient.Fetches(); fetches != 1 {
+		t.Errorf("expected a single fetch, got %d", fetches)
+	}
+
+	if err := c.PushSecret(context.Background(), []byte("new"), v1alpha1.PushSecretRemoteRef{RemoteKey: "API"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "API"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches := fakeClient.Fetches(); fetches != 2 {
+		t.Errorf("expected a fetch after pushing, got %d fetches", fetches)
+	}
+}
+
+func TestGetSecretCoalescing(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var fail error
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecretsFunc(func(request client.SecretsRequest) (*client.SecretsResponse, error) {
+		started <- struct{}{}
+		<-release
+		if fail != nil {
+			return nil, fail
+		}
+		return &client.SecretsResponse{Secrets: client.Secrets{"API": "key", "DB": "db"}}, nil
+	})
+	getConcurrently := func(c *Client) []error {
+		errs := make([]error, 8)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "API"})
+			}(i)
+		}
+		<-started
+		close(release)
+		wg.Wait()
+		return errs
+	}
+
+	c := &Client{onboardbase: fakeClient, project: "app", environment: "dev", snapshots: newSecretSnapshots()}
+	for _, err := range getConcurrently(c) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if fetches := fakeClient.Fetches(); fetches != 1 {
+		t.Errorf("expected concurrent calls to share a fetch, got %d fetches", fetches)
+	}
+
+	fail = errors.New("service unavailable")
+	release = make(chan struct{})
+	started = make(chan struct{}, 8)
+	c.snapshots = newSecretSnapshots()
+	for _, err := range getConcurrently(c) {
+		if !ErrorContains(err, "service unavailable") {
+			t.Errorf("expected the fetch error for every caller, got %v", err)
+		}
+	}
+
+	fail = nil
+	release = make(chan struct{})
+	started = make(chan struct{}, 1)
+	c.snapshots = newSecretSnapshots()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := c.GetSecret(ctx, esv1beta1.ExternalSecretDataRemoteRef{Key: "API"})
+		done <- err
+	}()
+	<-started
+	fetches := fakeClient.Fetches()
+	cancel()
+	if err := <-done; !ErrorContains(err, context.Canceled.Error()) {
+		t.Errorf("expected a canceled caller to stop waiting, got %v", err)
+	}
+	close(release)
+	out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "DB"})
+	if err != nil || string(out) != "db" {
+		t.Fatalf("unexpected result after cancellation: %q, %v", out, err)
+	}
+	if got := fakeClient.Fetches(); got != fetches {
+		t.Errorf("expected the fetch to outlive the canceled caller, got %d more fetches", got-fetches)
+	}
+}
+
+func TestGetSecretReferences(t *testing.T) {
+	values := map[string]string{
+		"DB_PASSWORD":  "ref:SHARED_DB",
+		"SHARED_DB":    "ref: PRIMARY_DB",
+		"PRIMARY_DB":   "s3cr3t",
+		"LOOP_A":       "ref:LOOP_B",
+		"LOOP_B":       "ref:LOOP_A",
+		"DANGLING":     "ref:MISSING",
+		"NOT_RESOLVED": "reference:PRIMARY_DB",
+	}
+	for i := 0; i <= maxReferenceDepth; i++ {
+		values[fmt.Sprintf("CHAIN_%d", i)] = fmt.Sprintf("ref:CHAIN_%d", i+1)
+	}
+	values[fmt.Sprintf("CHAIN_%d", maxReferenceDepth+1)] = "end"
+
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithValueFunc(func(request client.SecretRequest) (*client.SecretResponse, error) {
+		value, ok := values[request.Name]
+		if !ok {
+			return nil, client.ErrSecretNotFound
+		}
+		return &client.SecretResponse{Name: request.Name, Value: value}, nil
+	})
+	c := Client{onboardbase: fakeClient, referencePrefix: "ref:"}
+
+	testCases := []struct {
+		label       string
+		key         string
+		expected    string
+		expectError string
+	}{
+		{label: "chained references", key: "DB_PASSWORD", expected: "s3cr3t"},
+		{label: "other prefixes kept", key: "NOT_RESOLVED", expected: "reference:PRIMARY_DB"},
+		{label: "cycle", key: "LOOP_A", expectError: "reference cycle: LOOP_A -> LOOP_B -> LOOP_A"},
+		{label: "missing target", key: "DANGLING", expectError: "unable to resolve reference DANGLING -> MISSING"},
+		{label: "too deep", key: "CHAIN_0", expectError: fmt.Sprintf("more than %d references followed", maxReferenceDepth)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: tc.key})
+			if !ErrorContains(err, tc.expectError) {
+				t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && string(out) != tc.expected {
+				t.Errorf("unexpected secret data: expected %q, got %q", tc.expected, out)
+			}
+		})
+	}
+
+	c.referencePrefix = ""
+	if out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "DB_PASSWORD"}); err != nil || string(out) != "ref:SHARED_DB" {
+		t.Errorf("expected references not to be resolved by default, got %q, %v", out, err)
+	}
+}
+
+func TestGetSecretDockerConfigJSON(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{
+			"GHCR_REGISTRY":   "ghcr.io",
+			"GHCR_USERNAME":   "bot",
+			"GHCR_PASSWORD":   "s3cr3t",
+			"GHCR_EMAIL":      "bot@example.com",
+			"HUB_REGISTRY":    "docker.io",
+			"HUB_USERNAME":    "bot",
+			"HUB_PASSWORD":    "",
+			"PLAIN_REGISTRY":  "quay.io",
+			"PLAIN_USERNAME":  "bot",
+			"PLAIN_PASSWORD":  "pw",
+			"UNRELATED_VALUE": "x",
+		},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev", snapshots: newSecretSnapshots()}
+
+	testCases := []struct {
+		label       string
+		key         string
+		expected    string
+		expectError string
+	}{
+		{
+			label:    "with email",
+			key:      "GHCR?assemble=dockerconfigjson",
+			expected: `{"auths":{"ghcr.io":{"username":"bot","password":"s3cr3t","email":"bot@example.com","auth":"Ym90OnMzY3IzdA=="}}}`,
+		},
+		{
+			label:    "without email",
+			key:      "PLAIN?assemble=DockerConfigJSON",
+			expected: `{"auths":{"quay.io":{"username":"bot","password":"pw","auth":"Ym90OnB3"}}}`,
+		},
+		{label: "empty password", key: "HUB?assemble=dockerconfigjson", expectError: "cannot build docker config HUB: missing HUB_PASSWORD"},
+		{label: "missing keys", key: "UNRELATED?assemble=dockerconfigjson", expectError: "missing UNRELATED_REGISTRY, UNRELATED_USERNAME, UNRELATED_PASSWORD"},
+		{label: "unknown assembly", key: "GHCR?assemble=netrc", expectError: `unknown assemble value "netrc"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: tc.key})
+			if !ErrorContains(err, tc.expectError) {
+				t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && string(out) != tc.expected {
+				t.Errorf("unexpected docker config: expected %s, got %s", tc.expected, out)
+			}
+		})
+	}
+	if fetches := fakeClient.Fetches(); fetches != 1 {
+		t.Errorf("expected a single fetch, got %d", fetches)
+	}
+}
+
+func TestGetSecretRawPayloads(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		RawPayloads: []string{"plain text", `{"key":"A"}`},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev", rawPayloads: true}
+
+	out, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: allSecretsKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `["plain text","{\"key\":\"A\"}"]`; string(out) != want {
+		t.Errorf("unexpected secret data: expected %s, got %s", want, out)
+	}
+
+	c.keys = keyFilter{denied: []string{"A"}}
+	if _, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: allSecretsKey}); !ErrorContains(err, errRawPayloadsFiltered) {
+		t.Errorf("expected raw payloads to be refused past a key filter, got %v", err)
+	}
+}
+
+func TestGetAllSecretsPreservesPathKeys(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"config/app.yaml": "a: 1", "config/db/pool.yaml": "size: 5", "API_KEY": "3a3ea4f5"},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+
+	path := "config/"
+	out, err := c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{Path: &path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string][]byte{"config/app.yaml": []byte("a: 1"), "config/db/pool.yaml": []byte("size: 5")}
+	if !cmp.Equal(out, want) {
+		t.Errorf("unexpected secrets: expected %v, got %v", want, out)
+	}
+}
+
+func TestGetAllSecretsEmptyResult(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"API_KEY": "3a3ea4f5"},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+	noMatch := &esv1beta1.FindName{RegExp: "^DB_"}
+
+	testCases := []struct {
+		label       string
+		name        *esv1beta1.FindName
+		tags        map[string]string
+		expected    map[string][]byte
+		expectError string
+	}{
+		{label: "empty map by default", name: noMatch, expected: map[string][]byte{}},
+		{label: "error on empty", name: noMatch, tags: map[string]string{errorOnEmptyTag: "true"}, expectError: "no secrets matched filter"},
+		{label: "error on empty with matches", tags: map[string]string{errorOnEmptyTag: "true"}, expected: map[string][]byte{"API_KEY": []byte("3a3ea4f5")}},
+		{label: "invalid tag", tags: map[string]string{errorOnEmptyTag: "always"}, expectError: "invalid errorOnEmpty tag"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			out, err := c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{Name: tc.name, Tags: tc.tags})
+			if !ErrorContains(err, tc.expectError) {
+				t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && !cmp.Equal(out, tc.expected) {
+				t.Errorf("unexpected secrets: expected %v, got %v", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestGetSecretKey(t *testing.T) {
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"},
+		Data:       map[string][]byte{"signingKey": []byte("shared-secret")},
+	}).Build()
+	c := Client{kube: kube, namespace: "default", storeKind: esv1beta1.SecretStoreKind}
+
+	key, err := c.getSecretKey(context.Background(), v1.SecretKeySelector{Name: "gateway", Key: "signingKey"})
+	if err != nil || string(key) != "shared-secret" {
+		t.Errorf("unexpected key: %q, %v", key, err)
+	}
+	if _, err := c.getSecretKey(context.Background(), v1.SecretKeySelector{Name: "gateway", Key: "other"}); !ErrorContains(err, "key 'other' not found in secret 'gateway'") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c.storeKind = esv1beta1.ClusterSecretStoreKind
+	if _, err := c.getSecretKey(context.Background(), v1.SecretKeySelector{Name: "gateway", Key: "signingKey"}); !ErrorContains(err, "missing namespace of secret gateway") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetAuthCredentialFiles(t *testing.T) {
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "passcode", Namespace: "default"},
+		Data:       map[string][]byte{"passcode": []byte("secret-passcode")},
+	}).Build()
+	c := Client{kube: kube, namespace: "default", storeKind: esv1beta1.SecretStoreKind, store: &esv1beta1.OnboardbaseProvider{
+		Auth: &esv1beta1.OnboardbaseAuth{
+			OnboardbaseAPIKeyFile: "/mnt/onboardbase/apiKey",
+			OnboardbasePasscode:   v1.SecretKeySelector{Name: "passcode", Key: "passcode"},
+		},
+	}}
+
+	if err := c.setAuth(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.onboardbaseAPIKey != "" || c.onboardbasePasscode != "secret-passcode" {
+		t.Errorf("unexpected credentials: %q, %q", c.onboardbaseAPIKey, c.onboardbasePasscode)
+	}
+
+	c.kube = clientfake.NewClientBuilder().Build()
+	c.onboardbasePasscode = ""
+	c.store.Auth.OnboardbasePasscodeFile = "/mnt/onboardbase/passcode"
+	if err := c.setAuth(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.onboardbasePasscode != "" {
+		t.Errorf("expected the passcode to be left to the file, got %q", c.onboardbasePasscode)
+	}
+}
+
+func TestResolveEnvironment(t *testing.T) {
+	kube := clientfake.NewClientBuilder().WithObjects(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-a",
+			Labels:      map[string]string{"onboardbase.com/environment": "staging"},
+			Annotations: map[string]string{"onboardbase.com/environment": "qa"},
+		},
+	}).Build()
+	testCases := []struct {
+		label       string
+		namespace   string
+		from        *esv1beta1.OnboardbaseNamespaceKey
+		expected    string
+		expectError string
+	}{
+		{label: "store environment", namespace: "team-a", expected: "development"},
+		{label: "label", namespace: "team-a", from: &esv1beta1.OnboardbaseNamespaceKey{Label: "onboardbase.com/environment"}, expected: "staging"},
+		{label: "annotation", namespace: "team-a", from: &esv1beta1.OnboardbaseNamespaceKey{Annotation: "onboardbase.com/environment"}, expected: "qa"},
+		{label: "no namespace", from: &esv1beta1.OnboardbaseNamespaceKey{Label: "onboardbase.com/environment"}, expected: "development"},
+		{
+			label:       "missing label",
+			namespace:   "team-a",
+			from:        &esv1beta1.OnboardbaseNamespaceKey{Label: "env"},
+			expectError: "namespace team-a has no label env naming the environment",
+		},
+		{
+			label:       "missing namespace",
+			namespace:   "team-b",
+			from:        &esv1beta1.OnboardbaseNamespaceKey{Label: "env"},
+			expectError: "unable to get namespace team-b to resolve the environment",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := Client{kube: kube, namespace: tc.namespace, store: &esv1beta1.OnboardbaseProvider{
+				Environment:              "development",
+				EnvironmentFromNamespace: tc.from,
+			}}
+			environment, err := c.resolveEnvironment(context.Background())
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if environment != tc.expected {
+				t.Errorf("unexpected environment: expected %q, got %q", tc.expected, environment)
+			}
+		})
+	}
+}
+
+func TestKeyFilter(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"APP_TOKEN": "1", "APP_ADMIN_TOKEN": "2", "DB_PASSWORD": "3"},
+	}, nil)
+	fakeClient.WithValue(client.SecretRequest{Project: "app", Environment: "dev", Name: "DB_PASSWORD"}, &client.SecretResponse{Name: "DB_PASSWORD", Value: "3"}, nil)
+	c := Client{
+		onboardbase: fakeClient,
+		project:     "app",
+		environment: "dev",
+		keys:        keyFilter{allowed: []string{"APP_*", "DB_*"}, denied: []string{"*_ADMIN_*", "DB_PASSWORD"}},
+	}
+
+	out, err := c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string][]byte{"APP_TOKEN": []byte("1")}; !cmp.Equal(out, want) {
+		t.Errorf("unexpected secrets: expected %v, got %v", want, out)
+	}
+
+	if _, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "DB_PASSWORD"}); !ErrorContains(err, "secret not found") {
+		t.Errorf("expected a denied key not to be found, got %v", err)
+	}
+	secret, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "DB_PASSWORD?default=none"})
+	if err != nil || string(secret) != "none" {
+		t.Errorf("expected the default for a denied key, got %q, %v", secret, err)
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"k8s/myapp/TOKEN": "1", "k8s/myapp/ADMIN": "2", "MANUAL": "3"},
+	}, nil)
+	fakeClient.WithValue(client.SecretRequest{Project: "app", Environment: "dev", Name: "k8s/myapp/TOKEN"}, &client.SecretResponse{Name: "k8s/myapp/TOKEN", Value: "1"}, nil)
+	c := Client{
+		onboardbase: fakeClient,
+		project:     "app",
+		environment: "dev",
+		keys:        keyFilter{denied: []string{"ADMIN"}, prefix: "k8s/myapp/"},
+	}
+
+	out, err := c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := map[string][]byte{"TOKEN": []byte("1")}; !cmp.Equal(out, want) {
+		t.Errorf("unexpected secrets: expected %v, got %v", want, out)
+	}
+	secret, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "TOKEN"})
+	if err != nil || string(secret) != "1" {
+		t.Errorf("expected the prefixed secret, got %q, %v", secret, err)
+	}
+
+	if err := c.PushSecret(context.Background(), []byte("2"), v1alpha1.PushSecretRemoteRef{RemoteKey: "TOKEN"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updates := fakeClient.Updates(); len(updates) != 1 || updates[0].Secrets[0].Key != "k8s/myapp/TOKEN" {
+		t.Errorf("expected the pushed key to be prefixed, got %+v", updates)
+	}
+	response, err := c.DeleteSecrets(context.Background(), []esv1beta1.PushRemoteRef{v1alpha1.PushSecretRemoteRef{RemoteKey: "TOKEN"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted := response.Deleted(); len(deleted) != 1 || deleted[0].Name != "k8s/myapp/TOKEN" {
+		t.Errorf("expected the deleted key to be prefixed, got %+v", deleted)
+	}
+}
+
+func TestGetAllSecretsMultipleProjects(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecretsFunc(func(request client.SecretsRequest) (*client.SecretsResponse, error) {
+		switch request.Project {
+		case "app":
+			return &client.SecretsResponse{Secrets: client.Secrets{"TOKEN": "1"}}, nil
+		case "billing":
+			return &client.SecretsResponse{Secrets: client.Secrets{"TOKEN": "2"}}, nil
+		}
+		return nil, fmt.Errorf("project %s not found", request.Project)
+	})
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+
+	testCases := []struct {
+		label       string
+		tags        map[string]string
+		expected    map[string][]byte
+		expectError string
+	}{
+		{label: "store project", expected: map[string][]byte{"TOKEN": []byte("1")}},
+		{label: "listed projects", tags: map[string]string{"projects": "app, billing"}, expected: map[string][]byte{"app/TOKEN": []byte("1"), "billing/TOKEN": []byte("2")}},
+		{label: "failed project skipped", tags: map[string]string{"projects": "app,missing"}, expected: map[string][]byte{"app/TOKEN": []byte("1")}},
+		{label: "failed project with strict", tags: map[string]string{"projects": "app,missing", "strict": "true"}, expectError: "could not get secrets of project missing"},
+		{label: "every project failed", tags: map[string]string{"projects": "missing"}, expectError: "could not get secrets of any project"},
+		{label: "invalid strict tag", tags: map[string]string{"projects": "app", "strict": "yes please"}, expectError: "invalid strict tag"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			out, err := c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{Tags: tc.tags})
+			if !ErrorContains(err, tc.expectError) {
+				t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && !cmp.Equal(out, tc.expected) {
+				t.Errorf("unexpected secrets: expected %v, got %v", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestGetAllSecretsParentEnvironment(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecretsFunc(func(request client.SecretsRequest) (*client.SecretsResponse, error) {
+		switch request.Environment {
+		case "base":
+			return &client.SecretsResponse{Secrets: client.Secrets{"LOG_LEVEL": "info", "DB_HOST": "db.internal"}}, nil
+		case "dev":
+			return &client.SecretsResponse{Secrets: client.Secrets{"LOG_LEVEL": "debug", "TOKEN": "dev"}}, nil
+		}
+		return nil, fmt.Errorf("environment %s not found", request.Environment)
+	})
+
+	testCases := []struct {
+		label       string
+		parent      string
+		tags        map[string]string
+		expected    map[string][]byte
+		expectError string
+	}{
+		{label: "no parent", expected: map[string][]byte{"LOG_LEVEL": []byte("debug"), "TOKEN": []byte("dev")}},
+		{label: "child overrides parent", parent: "base", expected: map[string][]byte{"LOG_LEVEL": []byte("debug"), "DB_HOST": []byte("db.internal"), "TOKEN": []byte("dev")}},
+		{label: "missing parent", parent: "gone", expectError: "environment gone not found"},
+		{label: "keys prefixed with their environment", parent: "base", tags: map[string]string{environmentPrefixTag: "true"}, expected: map[string][]byte{"dev/LOG_LEVEL": []byte("debug"), "base/DB_HOST": []byte("db.internal"), "dev/TOKEN": []byte("dev")}},
+		{label: "keys prefixed without a parent", tags: map[string]string{environmentPrefixTag: "true"}, expected: map[string][]byte{"dev/LOG_LEVEL": []byte("debug"), "dev/TOKEN": []byte("dev")}},
+		{label: "invalid prefix tag", tags: map[string]string{environmentPrefixTag: "sure"}, expectError: "invalid prefixEnvironment tag"},
+		{label: "prefix with projects", tags: map[string]string{environmentPrefixTag: "true", projectsTag: "app"}, expectError: "the prefixEnvironment tag cannot be combined with the projects tag"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := Client{onboardbase: fakeClient, project: "app", environment: "dev", parentEnvironment: tc.parent}
+			out, err := c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{Tags: tc.tags})
+			if !ErrorContains(err, tc.expectError) {
+				t.Fatalf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && !cmp.Equal(out, tc.expected) {
+				t.Errorf("unexpected secrets: expected %v, got %v", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestTokenScopedProjectOverrides(t *testing.T) {
+	c := Client{onboardbase: &fake.OnboardbaseClient{}, project: "app", environment: "dev", tokenScopedProject: true}
+
+	_, err := c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: "billing:staging:" + validSecretName})
+	if want := "remote key billing:staging:API_KEY overrides the project"; !ErrorContains(err, want) {
+		t.Errorf("unexpected error: %v, expected: %q", err, want)
+	}
+	_, err = c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{Tags: map[string]string{projectsTag: "billing"}})
+	if want := "the projects tag overrides the project"; !ErrorContains(err, want) {
+		t.Errorf("unexpected error: %v, expected: %q", err, want)
+	}
+}
+
+func TestRefTimeout(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithValue(makeValidAPIRequest(), makeValidAPIOutput(), nil)
+	c := Client{onboardbase: fakeClient, maxRefTimeout: time.Minute}
+
+	ctx, cancel, err := c.withRefTimeout(context.Background(), 30*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > 30*time.Second {
+		t.Errorf("expected a deadline within 30s, got %v", deadline)
+	}
+
+	testCases := []struct {
+		label       string
+		key         string
+		tags        map[string]string
+		expectError string
+	}{
+		{label: "timeout within the maximum", key: validSecretName + "?timeout=30s"},
+		{label: "timeout over the maximum", key: validSecretName + "?timeout=2m", expectError: "timeout 2m0s exceeds the store's maxRefTimeout of 1m0s"},
+		{label: "negative timeout", key: validSecretName + "?timeout=-1s", expectError: `invalid timeout "-1s": must be positive`},
+		{label: "malformed timeout", key: validSecretName + "?timeout=soon", expectError: `invalid timeout "soon"`},
+		{label: "find timeout over the maximum", tags: map[string]string{timeoutTag: "1h"}, expectError: "timeout 1h0m0s exceeds the store's maxRefTimeout of 1m0s"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			var err error
+			if tc.key != "" {
+				_, err = c.GetSecret(context.Background(), esv1beta1.ExternalSecretDataRemoteRef{Key: tc.key})
+			} else {
+				_, err = c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{Tags: tc.tags})
+			}
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestMergeJSON(t *testing.T) {
+	remote := []byte(`{"db": {"user": "app", "password": "old"}, "tags": ["a"], "remoteOnly": true}`)
+	local := []byte(`{"db": {"password": "new", "port": 5432}, "tags": ["b"]}`)
+	testCases := []struct {
+		label       string
+		remote      []byte
+		strategy    esv1beta1.OnboardbasePushMergeStrategy
+		expected    string
+		expectError string
+	}{
+		{
+			label:    "local wins",
+			remote:   remote,
+			strategy: esv1beta1.OnboardbasePushMergeLocalWins,
+			expected: `{"db":{"password":"new","port":5432,"user":"app"},"remoteOnly":true,"tags":["b"]}`,
+		},
+		{
+			label:    "remote wins",
+			remote:   remote,
+			strategy: esv1beta1.OnboardbasePushMergeRemoteWins,
+			expected: `{"db":{"password":"old","port":5432,"user":"app"},"remoteOnly":true,"tags":["a"]}`,
+		},
+		{
+			label:    "numbers beyond float64 precision",
+			remote:   []byte(`{"id": 9007199254740993, "ratio": 1.50, "huge": 123456789012345678901234567890}`),
+			strategy: esv1beta1.OnboardbasePushMergeRemoteWins,
+			expected: `{"db":{"password":"new","port":5432},"huge":123456789012345678901234567890,"id":9007199254740993,"ratio":1.50,"tags":["b"]}`,
+		},
+		{
+			label:       "remote with trailing data",
+			remote:      []byte(`{"a": 1} {"b": 2}`),
+			strategy:    esv1beta1.OnboardbasePushMergeLocalWins,
+			expectError: "remote value of API_KEY is not a JSON object",
+		},
+		{
+			label:       "remote not JSON",
+			remote:      []byte("plain"),
+			strategy:    esv1beta1.OnboardbasePushMergeLocalWins,
+			expectError: "remote value of API_KEY is not a JSON object",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			out, err := mergeJSON(validSecretName, tc.remote, local, tc.strategy)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && string(out) != tc.expected {
+				t.Errorf("unexpected value: expected %s, got %s", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestPushAllSecrets(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"APP_config": `{"a":1,"b":1}`},
+	}, nil)
+	c := Client{
+		onboardbase:       fakeClient,
+		project:           "app",
+		environment:       "dev",
+		pushKeyPrefix:     "APP_",
+		pushMergeStrategy: esv1beta1.OnboardbasePushMergeLocalWins,
+		maxValueSize:      16,
+	}
+	data := map[string][]byte{"config": []byte(`{"b":2}`), "token": []byte("t"), "cert": []byte(strings.Repeat("x", 17))}
+
+	_, err := c.PushAllSecrets(context.Background(), data, v1alpha1.PushSecretRemoteRef{})
+	if !ErrorContains(err, "could not push keys: APP_cert: value of 17 bytes exceeds 16 bytes") {
+		t.Errorf("expected a per-key error, got %v", err)
+	}
+	if len(fakeClient.Updates()) != 0 {
+		t.Fatalf("expected nothing to be pushed when a key fails")
+	}
+
+	c.maxValueSize = 0
+	pushed, err := c.PushAllSecrets(context.Background(), data, v1alpha1.PushSecretRemoteRef{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"cert": "APP_cert", "config": "APP_config", "token": "APP_token"}, pushed); diff != "" {
+		t.Errorf("unexpected pushed keys (-want +got):\n%s", diff)
+	}
+	expected := []client.UpdateSecretsRequest{{Project: "app", Environment: "dev", Secrets: client.RawSecrets{
+		{Key: "APP_cert", Value: strings.Repeat("x", 17)},
+		{Key: "APP_config", Value: `{"a":1,"b":2}`},
+		{Key: "APP_token", Value: "t"},
+	}}}
+	if diff := cmp.Diff(expected, fakeClient.Updates()); diff != "" {
+		t.Errorf("unexpected updates (-want +got):\n%s", diff)
+	}
+
+	if _, err := c.PushAllSecrets(context.Background(), data, v1alpha1.PushSecretRemoteRef{RemoteKey: "SECRET"}); !ErrorContains(err, `remoteKey "SECRET" must be empty`) {
+		t.Errorf("expected a remote key error, got %v", err)
+	}
+}
+
+func TestPushSecretMerge(t *testing.T) {
+	ref := v1alpha1.PushSecretRemoteRef{RemoteKey: validSecretName}
+	request := client.SecretRequest{Project: "app", Environment: "dev", Name: validSecretName}
+
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithValue(request, &client.SecretResponse{Name: validSecretName, Value: `{"a":1,"b":1}`}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev", pushMergeStrategy: esv1beta1.OnboardbasePushMergeLocalWins}
+	if err := c.PushSecret(context.Background(), []byte(`{"b":2}`), ref); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	missingClient := &fake.OnboardbaseClient{}
+	missingClient.WithValue(request, nil, fmt.Errorf("%w", client.ErrSecretNotFound))
+	c.onboardbase = missingClient
+	if err := c.PushSecret(context.Background(), []byte(`{"b":2}`), ref); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		fakeClient *fake.OnboardbaseClient
+		expected   string
+	}{
+		{fakeClient: fakeClient, expected: `{"a":1,"b":2}`},
+		{fakeClient: missingClient, expected: `{"b":2}`},
+	} {
+		updates := tc.fakeClient.Updates()
+		if len(updates) != 1 || updates[0].Secrets[0].Value != tc.expected {
+			t.Errorf("unexpected push: expected %s, got %+v", tc.expected, updates)
+		}
+	}
+}
+
+func TestPushSecretReadAfterWrite(t *testing.T) {
+	ref := v1alpha1.PushSecretRemoteRef{RemoteKey: validSecretName}
+	reads := 0
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithValueFunc(func(request client.SecretRequest) (*client.SecretResponse, error) {
+		reads++
+		if reads < 3 {
+			return &client.SecretResponse{Name: request.Name, Value: "old"}, nil
+		}
+		return &client.SecretResponse{Name: request.Name, Value: "new"}, nil
+	})
+	c := Client{
+		onboardbase:    fakeClient,
+		project:        "app",
+		environment:    "dev",
+		readAfterWrite: &readAfterWrite{timeout: time.Second, backoff: client.ConstantBackoff{Interval: time.Millisecond}},
+	}
+	if err := c.PushSecret(context.Background(), []byte("new"), ref); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reads != 3 {
+		t.Errorf("expected to read until the new value is visible, got %d reads", reads)
+	}
+
+	c.readAfterWrite.timeout = 20 * time.Millisecond
+	err := c.PushSecret(context.Background(), []byte("newer"), ref)
+	if !ErrorContains(err, "secret API_KEY was pushed but its new value was not readable within 20ms") {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+}
+
+func TestRotateSecret(t *testing.T) {
+	ref := v1alpha1.PushSecretRemoteRef{RemoteKey: validSecretName}
+	generate := func(value string, err error) func() ([]byte, error) {
+		return func() ([]byte, error) { return []byte(value), err }
+	}
+	testCases := []struct {
+		label        string
+		generate     func() ([]byte, error)
+		maxValueSize int
+		expected     string
+		expectError  string
+	}{
+		{label: "rotated", generate: generate("new", nil), expected: "new"},
+		{label: "generator failure", generate: generate("", fmt.Errorf("no entropy")), expectError: "could not generate a new value for secret API_KEY: no entropy"},
+		{label: "push failure", generate: generate("too large", nil), maxValueSize: 4, expectError: "exceeds 4 bytes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			fakeClient := &fake.OnboardbaseClient{}
+			c := Client{onboardbase: fakeClient, maxValueSize: tc.maxValueSize}
+			out, err := c.RotateSecret(context.Background(), ref, tc.generate)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if string(out) != tc.expected {
+				t.Errorf("unexpected value: expected %q, got %q", tc.expected, out)
+			}
+			if pushed := len(fakeClient.Updates()) == 1; pushed != (err == nil) {
+				t.Errorf("unexpected pushes: %+v", fakeClient.Updates())
+			}
+		})
+	}
+}
+
+func TestGetAllSecretsRewrite(t *testing.T) {
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithSecrets(client.SecretsRequest{Project: "app", Environment: "dev"}, &client.SecretsResponse{
+		Secrets: client.Secrets{"APP_DB_PASSWORD": "s3cr3t", "APP_DB_USER": "app", "OTHER": "x"},
+	}, nil)
+	c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+
+	out, err := c.GetAllSecrets(context.Background(), esv1beta1.ExternalSecretFind{Name: &esv1beta1.FindName{RegExp: "^APP_"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err = utils.RewriteMap([]esv1beta1.ExternalSecretRewrite{
+		{Regexp: &esv1beta1.ExternalSecretRewriteRegexp{Source: "^APP_DB_(.*)$", Target: "db-$1"}},
+	}, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string][]byte{"db-PASSWORD": []byte("s3cr3t"), "db-USER": []byte("app")}
+	if !cmp.Equal(out, want) {
+		t.Errorf("unexpected secrets: expected %v, got %v", want, out)
+	}
+}
+
+func TestGetProperty(t *testing.T) {
+	value := []byte(`{"database": {"password": "s3cr3t", "ports": [5432, 5433], "a/b": {"m~n": "escaped"}}, "dotted.key": "dot",
+		"limits": {"ratio": 1.50, "max": 12345678901234567890, "enabled": true, "owner": null,
+		"id": 9007199254740993, "huge": -123456789012345678901234567890, "exp": 1.0000000000000001e400}}`)
+	testCases := []struct {
+		label       string
+		property    string
+		expected    string
+		expectError string
+	}{
+		{label: "dot path", property: "database.password", expected: "s3cr3t"},
+		{label: "dotted key", property: "dotted.key", expected: "dot"},
+		{label: "pointer", property: "pointer:/database/password", expected: "s3cr3t"},
+		{label: "pointer array index", property: "pointer:/database/ports/1", expected: "5433"},
+		{label: "pointer escaped tokens", property: "pointer:/database/a~1b/m~0n", expected: "escaped"},
+		{label: "pointer object", property: "pointer:/database/ports", expected: "[5432,5433]"},
+		{label: "malformed pointer", property: "pointer:database", expectError: "invalid JSON pointer"},
+		{label: "malformed escape", property: "pointer:/database/a~2b", expectError: "invalid JSON pointer"},
+		{label: "unresolved pointer", property: "pointer:/database/user", expectError: "does not exist"},
+		{label: "pointer index out of range", property: "pointer:/database/ports/2", expectError: "does not exist"},
+		{label: "missing dot path", property: "database.user", expectError: "does not exist"},
+		{label: "number", property: "limits.ratio", expected: "1.50"},
+		{label: "large number", property: "limits.max", expected: "12345678901234567890"},
+		{label: "boolean", property: "limits.enabled", expected: "true"},
+		{label: "null", property: "limits.owner", expected: "null"},
+		{label: "pointer number", property: "pointer:/limits/ratio", expected: "1.50"},
+		{label: "pointer large number", property: "pointer:/limits/max", expected: "12345678901234567890"},
+		{label: "pointer boolean", property: "pointer:/limits/enabled", expected: "true"},
+		{label: "pointer null", property: "pointer:/limits/owner", expected: "null"},
+		{label: "pointer through null", property: "pointer:/limits/owner/name", expectError: "does not exist"},
+		{label: "integer beyond float64 precision", property: "limits.id", expected: "9007199254740993"},
+		{label: "integer beyond 64 bits", property: "limits.huge", expected: "-123456789012345678901234567890"},
+		{label: "exponent beyond float64 range", property: "limits.exp", expected: "1.0000000000000001e400"},
+		{label: "pointer integer beyond float64 precision", property: "pointer:/limits/id", expected: "9007199254740993"},
+		{label: "pointer integer beyond 64 bits", property: "pointer:/limits/huge", expected: "-123456789012345678901234567890"},
+		{label: "pointer exponent beyond float64 range", property: "pointer:/limits/exp", expected: "1.0000000000000001e400"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			out, err := getProperty(value, validSecretName, tc.property)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && string(out) != tc.expected {
+				t.Errorf("unexpected value: expected %q, got %q", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestValidateScope(t *testing.T) {
+	projects := []client.Project{
+		{Title: "app", Environments: []string{"development", "production"}},
+		{Title: "billing", Environments: []string{"development"}},
+	}
+	testCases := []struct {
+		label       string
+		project     string
+		environment string
+		expectError string
+	}{
+		{label: "existing scope", project: "app", environment: "production"},
+		{label: "unknown project", project: "ap", environment: "production", expectError: `project "ap" not found, available projects: app, billing`},
+		{label: "unknown environment", project: "billing", environment: "production", expectError: `environment "production" not found in project "billing", available environments: development`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			err := validateScope(projects, tc.project, tc.environment)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestPushSecretValueSize(t *testing.T) {
+	ref := v1alpha1.PushSecretRemoteRef{RemoteKey: validSecretName}
+	testCases := []struct {
+		label        string
+		maxValueSize int
+		value        []byte
+		expectError  string
+	}{
+		{label: "within default limit", value: make([]byte, defaultMaxValueSize)},
+		{label: "exceeds default limit", value: make([]byte, defaultMaxValueSize+1), expectError: "value of 65537 bytes exceeds 65536 bytes"},
+		{label: "exceeds configured limit", maxValueSize: 4, value: []byte("12345"), expectError: "value of 5 bytes exceeds 4 bytes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			c := Client{onboardbase: &fake.OnboardbaseClient{}, maxValueSize: tc.maxValueSize}
+			err := c.PushSecret(context.Background(), tc.value, ref)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"CLUSTER": "eu-1", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	testCases := []struct {
+		label       string
+		in          string
+		expected    string
+		expectError string
+	}{
+		{label: "literal host", in: "https://public.onboardbase.com/api/v1/", expected: "https://public.onboardbase.com/api/v1/"},
+		{label: "variable", in: "https://${CLUSTER}.onboardbase.internal", expected: "https://eu-1.onboardbase.internal"},
+		{label: "empty variable", in: "https://api${EMPTY}.onboardbase.com", expected: "https://api.onboardbase.com"},
+		{label: "bare dollar kept", in: "https://$CLUSTER.onboardbase.internal", expected: "https://$CLUSTER.onboardbase.internal"},
+		{label: "unresolved", in: "https://${REGION}.${ZONE}.onboardbase.internal", expectError: "unresolved environment variables in \"https://${REGION}.${ZONE}.onboardbase.internal\": REGION, ZONE"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			out, err := expandEnv(tc.in, lookup)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if err == nil && out != tc.expected {
+				t.Errorf("unexpected value: expected %q, got %q", tc.expected, out)
+			}
+		})
+	}
+}
+
+func TestAuthProbe(t *testing.T) {
+	release := make(chan struct{})
+	fakeClient := &fake.OnboardbaseClient{}
+	fakeClient.WithAuthenticate(func() error {
+		<-release
+		return fmt.Errorf("invalid api key")
+	})
+
+	probe := startAuthProbe(fakeClient, time.Second, log)
+	if err := probe.result(); err != nil {
+		t.Errorf("expected a running probe not to report an error, got %v", err)
+	}
+	close(release)
+	<-probe.done
+	if err := probe.result(); !ErrorContains(err, "authentication probe failed: invalid api key") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestProbeAuthentication(t *testing.T) {
+	testCases := []struct {
+		label       string
+		probes      int
+		results     []error
+		expected    esv1beta1.ValidationResult
+		expectError string
+		expectCalls int
+	}{
+		{label: "all probes succeed", results: []error{nil, nil, nil}, expected: esv1beta1.ValidationResultReady, expectCalls: 3},
+		{label: "one probe fails", results: []error{nil, fmt.Errorf("timeout"), nil}, expected: esv1beta1.ValidationResultUnknown, expectCalls: 3},
+		{label: "majority fails", results: []error{fmt.Errorf("timeout"), fmt.Errorf("invalid api key")}, expected: esv1beta1.ValidationResultError, expectError: "2 of 2 validation probes failed: invalid api key", expectCalls: 2},
+		{label: "single probe", probes: 1, results: []error{fmt.Errorf("invalid api key")}, expected: esv1beta1.ValidationResultError, expectError: "1 of 1 validation probes failed", expectCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			var calls int
+			fakeClient := &fake.OnboardbaseClient{}
+			fakeClient.WithAuthenticate(func() error {
+				calls++
+				return tc.results[calls-1]
+			})
+			c := Client{onboardbase: fakeClient, validationProbes: tc.probes}
+			result, err := c.probeAuthentication(context.Background())
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if result != tc.expected {
+				t.Errorf("unexpected result: expected %s, got %s", tc.expected, result)
+			}
+			if calls != tc.expectCalls {
+				t.Errorf("unexpected number of probes: expected %d, got %d", tc.expectCalls, calls)
+			}
+		})
+	}
+}
+
+func TestProbePasscode(t *testing.T) {
+	testCases := []struct {
+		label       string
+		valid       bool
+		err         error
+		expected    esv1beta1.ValidationResult
+		expectError string
+	}{
+		{label: "passcode matches", valid: true, expected: esv1beta1.ValidationResultReady},
+		{label: "passcode mismatch", expected: esv1beta1.ValidationResultError, expectError: "passcode does not match secret encryption"},
+		{label: "API failure", err: fmt.Errorf("unable to load response"), expected: esv1beta1.ValidationResultUnknown},
+		{label: "empty environment", err: fmt.Errorf("%w", client.ErrNothingToVerify), expected: esv1beta1.ValidationResultReady},
+		{label: "server-side decryption", err: fmt.Errorf("%w", client.ErrVerifyServerSide), expected: esv1beta1.ValidationResultReady},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			fakeClient := &fake.OnboardbaseClient{}
+			fakeClient.WithVerifyPasscode(func() (bool, error) {
+				return tc.valid, tc.err
+			})
+			c := Client{onboardbase: fakeClient, project: "app", environment: "dev"}
+			result, err := c.probePasscode(context.Background())
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+			if result != tc.expected {
+				t.Errorf("unexpected result: expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidateStore(t *testing.T) {
+	makeStore := func(passcodeKey string, serverSideDecryption bool) *esv1beta1.SecretStore {
+		return &esv1beta1.SecretStore{
+			Spec: esv1beta1.SecretStoreSpec{
+				Provider: &esv1beta1.SecretStoreProvider{
+					Onboardbase: &esv1beta1.OnboardbaseProvider{
+						Auth: &esv1beta1.OnboardbaseAuth{
+							OnboardbaseAPIKey:   v1.SecretKeySelector{Name: "onboardbase", Key: "apiKey"},
+							OnboardbasePasscode: v1.SecretKeySelector{Key: passcodeKey},
+						},
+						ServerSideDecryption: serverSideDecryption,
+					},
+				},
+			},
+		}
+	}
+	invalidKeyPattern := makeStore("passcode", false)
+	invalidKeyPattern.Spec.Provider.Onboardbase.DeniedKeys = []string{"APP_["}
+	credentialFiles := makeStore("", false)
+	credentialFiles.Spec.Provider.Onboardbase.Auth = &esv1beta1.OnboardbaseAuth{
+		OnboardbaseAPIKeyFile:   "/mnt/onboardbase/apiKey",
+		OnboardbasePasscodeFile: "/mnt/onboardbase/passcode",
+	}
+	invalidTimeout := makeStore("passcode", false)
+	invalidTimeout.Spec.Provider.Onboardbase.BodyReadTimeout = &metav1.Duration{}
+	ambiguousEnvironment := makeStore("passcode", false)
+	ambiguousEnvironment.Spec.Provider.Onboardbase.EnvironmentFromNamespace = &esv1beta1.OnboardbaseNamespaceKey{Label: "env", Annotation: "env"}
+	invalidCircuit := makeStore("passcode", false)
+	invalidCircuit.Spec.Provider.Onboardbase.CircuitBreaker = &esv1beta1.OnboardbaseCircuitBreaker{
+		Reads:  &esv1beta1.OnboardbaseCircuit{FailureThreshold: 5},
+		Writes: &esv1beta1.OnboardbaseCircuit{},
+	}
+	invalidPayloadHost := makeStore("passcode", false)
+	invalidPayloadHost.Spec.Provider.Onboardbase.PayloadHosts = []string{"https://storage.example.com"}
+	invalidSuccessCodes := makeStore("passcode", false)
+	invalidSuccessCodes.Spec.Provider.Onboardbase.SuccessCodes = map[string][]int{"POST /secrets": {404}}
+	negativeVerbosity := makeStore("passcode", false)
+	verbosity := -1
+	negativeVerbosity.Spec.Provider.Onboardbase.LogVerbosity = &verbosity
+	encryptWithoutPasscode := makeStore("", true)
+	encryptWithoutPasscode.Spec.Provider.Onboardbase.EncryptPushedSecrets = true
+	invalidFallbackPasscode := makeStore("passcode", false)
+	invalidFallbackPasscode.Spec.Provider.Onboardbase.Auth.OnboardbaseFallbackPasscodes = []v1.SecretKeySelector{{Name: "previous"}}
+	invalidRetryableCode := makeStore("passcode", false)
+	invalidRetryableCode.Spec.Provider.Onboardbase.Retry = &esv1beta1.OnboardbaseRetry{RetryableStatusCodes: []int{408, 200}}
+	insecure := makeStore("passcode", false)
+	insecure.Spec.Provider.Onboardbase.InsecureSkipVerify = true
+	acknowledgedInsecure := makeStore("passcode", false)
+	acknowledgedInsecure.Spec.Provider.Onboardbase.InsecureSkipVerify = true
+	acknowledgedInsecure.Spec.Provider.Onboardbase.AllowInsecure = true
+	filteredRawPayloads := makeStore("passcode", false)
+	filteredRawPayloads.Spec.Provider.Onboardbase.RawPayloads = true
+	filteredRawPayloads.Spec.Provider.Onboardbase.AllowedKeys = []string{"APP_*"}
+	invalidReadAfterWrite := makeStore("passcode", false)
+	invalidReadAfterWrite.Spec.Provider.Onboardbase.ReadAfterWriteTimeout = &metav1.Duration{}
+	prefixedRawPayloads := makeStore("passcode", false)
+	prefixedRawPayloads.Spec.Provider.Onboardbase.RawPayloads = true
+	prefixedRawPayloads.Spec.Provider.Onboardbase.KeyPrefix = "k8s/"
+	negativeCompressionThreshold := makeStore("passcode", false)
+	negativeCompressionThreshold.Spec.Provider.Onboardbase.CompressPushes = true
+	negativeCompressionThreshold.Spec.Provider.Onboardbase.CompressionThreshold = -1
+	selfInheriting := makeStore("passcode", false)
+	selfInheriting.Spec.Provider.Onboardbase.Environment = "dev"
+	selfInheriting.Spec.Provider.Onboardbase.ParentEnvironment = "dev"
+	invalidMaxRefTimeout := makeStore("passcode", false)
+	invalidMaxRefTimeout.Spec.Provider.Onboardbase.MaxRefTimeout = &metav1.Duration{}
+	negativeSigningLimit := makeStore("passcode", false)
+	negativeSigningLimit.Spec.Provider.Onboardbase.RequestSigning = &esv1beta1.OnboardbaseRequestSigning{
+		KeySecretRef: v1.SecretKeySelector{Name: "signing", Key: "key"},
+		MaxBodySize:  -1,
+	}
+	testCases := []struct {
+		label       string
+		store       *esv1beta1.SecretStore
+		expectError string
+	}{
+		{label: "passcode configured", store: makeStore("passcode", false)},
+		{label: "passcode missing", store: makeStore("", false), expectError: "onboardbasePasscode.key is required unless serverSideDecryption is enabled"},
+		{label: "passcode not needed with server-side decryption", store: makeStore("", true)},
+		{label: "credentials from files", store: credentialFiles},
+		{label: "invalid key pattern", store: invalidKeyPattern, expectError: `invalid key pattern "APP_["`},
+		{label: "invalid timeout", store: invalidTimeout, expectError: "responseHeaderTimeout and bodyReadTimeout must be positive"},
+		{label: "ambiguous environment source", store: ambiguousEnvironment, expectError: "environmentFromNamespace must set exactly one of label and annotation"},
+		{label: "invalid circuit", store: invalidCircuit, expectError: "circuitBreaker failureThreshold must be at least 1"},
+		{label: "invalid payload host", store: invalidPayloadHost, expectError: `payloadHosts must be host names, e.g. storage.example.com, got "https://storage.example.com"`},
+		{label: "invalid success codes", store: invalidSuccessCodes, expectError: "success code 404 of operation POST /secrets is not a 2xx or 3xx status"},
+		{label: "negative log verbosity", store: negativeVerbosity, expectError: "logVerbosity must not be negative"},
+		{label: "encryption without passcode", store: encryptWithoutPasscode, expectError: "encryptPushedSecrets cannot be combined with serverSideDecryption"},
+		{label: "invalid fallback passcode", store: invalidFallbackPasscode, expectError: "onboardbaseFallbackPasscodes entries need a name and a key"},
+		{label: "invalid retryable status code", store: invalidRetryableCode, expectError: "retryable status code 200 is not a 4xx or 5xx status"},
+		{label: "insecure without acknowledgment", store: insecure, expectError: "set allowInsecure: true as well if that is intended"},
+		{label: "acknowledged insecure", store: acknowledgedInsecure},
+		{label: "raw payloads with a key filter", store: filteredRawPayloads, expectError: errRawPayloadsFiltered},
+		{label: "invalid read-after-write timeout", store: invalidReadAfterWrite, expectError: "readAfterWriteTimeout must be positive"},
+		{label: "raw payloads with a key prefix", store: prefixedRawPayloads, expectError: errRawPayloadsFiltered},
+		{label: "negative compression threshold", store: negativeCompressionThreshold, expectError: "compressionThreshold must not be negative"},
+		{label: "environment inheriting from itself", store: selfInheriting, expectError: `environment "dev" cannot inherit from itself`},
+		{label: "negative signing body limit", store: negativeSigningLimit, expectError: "requestSigning.maxBodySize must not be negative"},
+		{label: "invalid maximum ref timeout", store: invalidMaxRefTimeout, expectError: "maxRefTimeout must be positive"},
+	}
+
+	p := Provider{}
+	for _, tc := range testCases {
+		t.Run(tc.label, func(t *testing.T) {
+			err := p.ValidateStore(tc.store)
+			if !ErrorContains(err, tc.expectError) {
+				t.Errorf("unexpected error: %v, expected: %q", err, tc.expectError)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+const (
+	// projectsTag lists, comma-separated, the projects GetAllSecrets fetches
+	// instead of the store's project.
+	projectsTag = "projects"
+	// strictTag makes GetAllSecrets fail when any listed project fails.
+	strictTag = "strict"
+
+	// projectKeySeparator separates the project from the secret key in the
+	// keys returned for multiple projects.
+	projectKeySeparator = "/"
+
+	errGetProjectSecrets = "could not get secrets of project %s: %w"
+	errAllProjectsFailed = "could not get secrets of any project: %s"
+	errInvalidTag        = "invalid %s tag: %w"
+)
+
+// findProjects returns the projects listed in ref and whether a failure of
+// one of them fails the whole fetch. It returns no projects when ref does
+// not list any.
+func findProjects(ref esv1beta1.ExternalSecretFind) ([]string, bool, error) {
+	var projects []string
+	seen := map[string]bool{}
+	for _, project := range strings.Split(ref.Tags[projectsTag], ",") {
+		project = strings.TrimSpace(project)
+		if project == "" || seen[project] {
+			continue
+		}
+		seen[project] = true
+		projects = append(projects, project)
+	}
+
+	strict, err := findBoolTag(ref, strictTag)
+	if err != nil {
+		return nil, false, err
+	}
+	return projects, strict, nil
+}
+
+// findBoolTag returns the boolean value of the tag name of ref, false when
+// ref does not set it.
+func findBoolTag(ref esv1beta1.ExternalSecretFind, name string) (bool, error) {
+	value, ok := ref.Tags[name]
+	if !ok {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf(errInvalidTag, name, err)
+	}
+	return enabled, nil
+}
+
+// getProjectsSecrets fetches the secrets of the store's environment in each
+// of projects, prefixing keys with the project name. A project that fails is
+// logged and skipped, unless strict is set; the fetch only fails when every
+// project does.
+func (c *Client) getProjectsSecrets(ctx context.Context, projects []string, strict bool) (map[string][]byte, error) {
+	secrets := map[string][]byte{}
+	var failed []string
+	for _, project := range projects {
+		request := dClient.SecretsRequest{
+			Project:     project,
+			Environment: c.environment,
+		}
+		response, err := c.onboardbase.GetSecrets(ctx, request)
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf(errGetProjectSecrets, project, err)
+			}
+			c.logger().Error(err, "skipping project whose secrets could not be fetched", "project", project, "environment", c.environment)
+			failed = append(failed, project+": "+err.Error())
+			continue
+		}
+		c.logFetch(request, response)
+		for key, value := range c.keys.filter(externalSecretsFormat(response.Secrets)) {
+			secrets[project+projectKeySeparator+key] = value
+		}
+	}
+
+	if len(failed) == len(projects) {
+		return nil, fmt.Errorf(errAllProjectsFailed, strings.Join(failed, "; "))
+	}
+	return secrets, nil
+}
@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// jsonPointerPrefix selects RFC 6901 JSON Pointer syntax for ref.Property,
+// e.g. "pointer:/database/password". Without it, dot notation is used.
+const jsonPointerPrefix = "pointer:"
+
+const (
+	errPropertyNotFound   = "key %s does not exist in secret %s"
+	errInvalidJSONPointer = "invalid JSON pointer %q: %s"
+	errPointerNotJSON     = "secret %s is not valid JSON: %w"
+)
+
+// getProperty extracts a property from a JSON secret value. String leaves are
+// returned unquoted; any other leaf is returned as its exact JSON token, so
+// numbers keep their precision, even beyond that of a float64, and booleans
+// and null stay unquoted.
+func getProperty(value []byte, key, property string) ([]byte, error) {
+	if strings.HasPrefix(property, jsonPointerPrefix) {
+		return getPointerProperty(value, key, strings.TrimPrefix(property, jsonPointerPrefix))
+	}
+
+	payload := string(value)
+	if strings.Contains(property, ".") {
+		val := gjson.Get(payload, strings.ReplaceAll(property, ".", "\\."))
+		if val.Exists() {
+			return gjsonValue(val), nil
+		}
+	}
+	val := gjson.Get(payload, property)
+	if !val.Exists() {
+		return nil, fmt.Errorf(errPropertyNotFound, property, key)
+	}
+	return gjsonValue(val), nil
+}
+
+// decodeJSON decodes a single JSON document into v. Numbers decoded into an
+// interface{} are kept as json.Number, with their exact text, rather than
+// rounded to a float64, which cannot represent integers beyond 2^53.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+func gjsonValue(val gjson.Result) []byte {
+	if val.Type == gjson.String {
+		return []byte(val.String())
+	}
+	return []byte(val.Raw)
+}
+
+func getPointerProperty(value []byte, key, pointer string) ([]byte, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	var current json.RawMessage
+	if err := decodeJSON(value, &current); err != nil {
+		return nil, fmt.Errorf(errPointerNotJSON, key, err)
+	}
+
+	for _, token := range tokens {
+		var object map[string]json.RawMessage
+		var array []json.RawMessage
+		switch {
+		case json.Unmarshal(current, &object) == nil && object != nil:
+			next, ok := object[token]
+			if !ok {
+				return nil, fmt.Errorf(errPropertyNotFound, pointer, key)
+			}
+			current = next
+		case json.Unmarshal(current, &array) == nil && array != nil:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(array) || (len(token) > 1 && token[0] == '0') {
+				return nil, fmt.Errorf(errPropertyNotFound, pointer, key)
+			}
+			current = array[index]
+		default:
+			return nil, fmt.Errorf(errPropertyNotFound, pointer, key)
+		}
+	}
+
+	var str string
+	if bytes.HasPrefix(bytes.TrimSpace(current), []byte(`"`)) && json.Unmarshal(current, &str) == nil {
+		return []byte(str), nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, current); err != nil {
+		return nil, fmt.Errorf(errPointerNotJSON, key, err)
+	}
+	return compact.Bytes(), nil
+}
+
+// parseJSONPointer splits a JSON pointer into its unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf(errInvalidJSONPointer, pointer, "must be empty or start with '/'")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf(errInvalidJSONPointer, pointer, "'~' must be followed by '0' or '1'")
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
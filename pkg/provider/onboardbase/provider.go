@@ -17,7 +17,13 @@ package onboardbase
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
@@ -26,11 +32,26 @@ import (
 )
 
 const (
-	errNewClient    = "unable to create OnboardbaseClient : %s"
-	errInvalidStore = "invalid store: %s"
-	errOnboardbaseStore = "missing or invalid Onboardbase SecretStore"
+	errNewClient          = "unable to create OnboardbaseClient : %s"
+	errInvalidStore       = "invalid store: %s"
+	errOnboardbaseStore   = "missing or invalid Onboardbase SecretStore"
+	errInsecureWithoutAck = "insecureSkipVerify disables TLS verification of the Onboardbase API; set allowInsecure: true as well if that is intended"
+
+	errUnresolvedEnv      = "unresolved environment variables in %q: %s"
+	errUnknownProject     = "project %q not found, available projects: %s"
+	errUnknownEnvironment = "environment %q not found in project %q, available environments: %s"
 )
 
+// defaultHedgeDelay is used when hedgeReads is set without a hedgeDelay.
+const defaultHedgeDelay = 200 * time.Millisecond
+
+// defaultCompressionThreshold is used when compressPushes is set without a
+// compressionThreshold.
+const defaultCompressionThreshold = 8192
+
+// defaultCircuitCooldown is used when a circuit is set without a cooldown.
+const defaultCircuitCooldown = 30 * time.Second
+
 // Provider is a Onboardbase secrets provider implementing NewClient and ValidateStore for the esv1beta1.Provider interface.
 type Provider struct{}
 
@@ -45,7 +66,7 @@ func init() {
 }
 
 func (p *Provider) Capabilities() esv1beta1.SecretStoreCapabilities {
-	return esv1beta1.SecretStoreReadOnly
+	return esv1beta1.SecretStoreReadWrite
 }
 
 func (p *Provider) NewClient(ctx context.Context, store esv1beta1.GenericStore, kube kclient.Client, namespace string) (esv1beta1.SecretsClient, error) {
@@ -67,16 +88,179 @@ func (p *Provider) NewClient(ctx context.Context, store esv1beta1.GenericStore,
 	if err := client.setAuth(ctx); err != nil {
 		return nil, err
 	}
+	environment, err := client.resolveEnvironment(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(errNewClient, err)
+	}
+	if err := checkInheritance(environment, client.store.ParentEnvironment); err != nil {
+		return nil, fmt.Errorf(errNewClient, err)
+	}
 
 	onboardbase, err := dClient.NewOnboardbaseClient(client.onboardbaseAPIKey, client.onboardbasePasscode)
 	if err != nil {
 		return nil, fmt.Errorf(errNewClient, err)
 	}
+	if verbosity := client.store.LogVerbosity; verbosity != nil {
+		client.log = dClient.LimitVerbosity(log, *verbosity)
+		onboardbase.Log = client.log
+	}
+
+	if err := onboardbase.SetCredentialFiles(client.store.Auth.OnboardbaseAPIKeyFile, client.store.Auth.OnboardbasePasscodeFile); err != nil {
+		return nil, fmt.Errorf(errNewClient, err)
+	}
+	for _, selector := range client.store.Auth.OnboardbaseFallbackPasscodes {
+		passcode, err := client.getSecretKey(ctx, selector)
+		if err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+		onboardbase.FallbackPasscodes = append(onboardbase.FallbackPasscodes, string(passcode))
+	}
+	if client.store.APIHost != "" {
+		apiHost, err := expandEnv(client.store.APIHost, os.LookupEnv)
+		if err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+		if err := onboardbase.SetBaseURL(apiHost); err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+	}
+	if client.store.ReadAPIHost != "" {
+		readHost, err := expandEnv(client.store.ReadAPIHost, os.LookupEnv)
+		if err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+		if err := onboardbase.SetReadURL(readHost); err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+	}
+	if signing := client.store.RequestSigning; signing != nil {
+		key, err := client.getSecretKey(ctx, signing.KeySecretRef)
+		if err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+		onboardbase.SigningKey = key
+		onboardbase.SigningHeader = signing.Header
+		onboardbase.SigningMaxBodySize = signing.MaxBodySize
+	}
+	if graphQL := client.store.GraphQL; graphQL != nil {
+		onboardbase.SetGraphQL(graphQL.Path, graphQL.Query)
+	}
+	if client.store.InsecureSkipVerify {
+		if !client.store.AllowInsecure {
+			return nil, fmt.Errorf(errNewClient, errInsecureWithoutAck)
+		}
+		if err := onboardbase.SetInsecureSkipVerify(true); err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+		client.logger().Info("TLS verification of the Onboardbase API is disabled", "host", onboardbase.BaseURL().Host)
+	}
+	if len(client.store.TLSCipherSuites) > 0 {
+		if err := onboardbase.SetCipherSuites(client.store.TLSCipherSuites); err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+	}
+	if client.store.AcceptLanguage != "" {
+		onboardbase.AcceptLanguage = client.store.AcceptLanguage
+	}
+	onboardbase.ExtraHeaders = client.store.ExtraHeaders
+	if retry := client.store.Retry; retry != nil {
+		onboardbase.MaxRetries = retry.MaxRetries
+		onboardbase.Backoff = newBackoff(retry)
+		onboardbase.RetryableStatusCodes = retry.RetryableStatusCodes
+	}
+	if client.store.HedgeReads {
+		onboardbase.HedgeDelay = defaultHedgeDelay
+		if client.store.HedgeDelay != nil {
+			onboardbase.HedgeDelay = client.store.HedgeDelay.Duration
+		}
+	}
+	if client.store.CompressPushes {
+		onboardbase.CompressThreshold = defaultCompressionThreshold
+		if client.store.CompressionThreshold > 0 {
+			onboardbase.CompressThreshold = client.store.CompressionThreshold
+		}
+	}
+	if timeout := client.store.ResponseHeaderTimeout; timeout != nil {
+		if err := onboardbase.SetResponseHeaderTimeout(timeout.Duration); err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+	}
+	if timeout := client.store.BodyReadTimeout; timeout != nil {
+		onboardbase.BodyReadTimeout = timeout.Duration
+	}
+	onboardbase.SuccessCodes = client.store.SuccessCodes
+	onboardbase.PayloadHosts = client.store.PayloadHosts
+	if breaker := client.store.CircuitBreaker; breaker != nil {
+		onboardbase.ReadCircuit = newCircuitSettings(breaker.Reads)
+		onboardbase.WriteCircuit = newCircuitSettings(breaker.Writes)
+	}
+	onboardbase.StrictDecode = client.store.StrictDecode
+	onboardbase.AllowEmptyValues = client.store.AllowEmptyValues
+	onboardbase.ServerSideDecryption = client.store.ServerSideDecryption
+	onboardbase.TokenScopedProject = client.store.TokenScopedProject
+	if client.store.DuplicateKeyPolicy != "" {
+		onboardbase.DuplicateKeyPolicy = dClient.DuplicateKeyPolicy(client.store.DuplicateKeyPolicy)
+	}
+	if err := onboardbase.SetFailoverURLs(client.store.FailoverHosts); err != nil {
+		return nil, fmt.Errorf(errNewClient, err)
+	}
+
+	if client.store.ValidateScope {
+		projects, err := onboardbase.ListProjects(ctx)
+		if err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+		if err := validateScope(projects, client.store.Project, environment); err != nil {
+			return nil, fmt.Errorf(errNewClient, err)
+		}
+	}
+
+	if interval := client.store.KeepaliveInterval; interval != nil {
+		keepaliveCtx, cancel := context.WithCancel(context.Background())
+		onboardbase.StartKeepalive(keepaliveCtx, interval.Duration)
+		client.stopKeepalive = cancel
+	}
 
 	client.onboardbase = onboardbase
+	if client.store.AsyncAuthProbe {
+		client.authProbe = startAuthProbe(onboardbase, asyncAuthProbeTimeout, client.logger())
+	}
 	client.project = client.store.Project
-	client.environment = client.store.Environment
-	
+	client.environment = environment
+	client.parentEnvironment = client.store.ParentEnvironment
+	client.tokenScopedProject = client.store.TokenScopedProject
+	client.trimSpace = client.store.TrimSpace
+	client.referencePrefix = client.store.ReferencePrefix
+	client.validationProbes = client.store.ValidationProbes
+	client.snapshots = newSecretSnapshots()
+	client.keys = keyFilter{allowed: client.store.AllowedKeys, denied: client.store.DeniedKeys, prefix: client.store.KeyPrefix}
+	if cache := client.store.Cache; cache != nil {
+		var maxStaleness time.Duration
+		if cache.MaxStaleness != nil {
+			maxStaleness = cache.MaxStaleness.Duration
+		}
+		client.cache = newSecretCache(kube, namespace, cache.SecretName, maxStaleness)
+	}
+	client.rawPayloads = client.store.RawPayloads
+	onboardbase.RawPayloads = client.store.RawPayloads
+	client.maxValueSize = client.store.MaxValueSize
+	client.pushMergeStrategy = client.store.PushMergeStrategy
+	client.pushKeyPrefix = client.store.PushKeyPrefix
+	if timeout := client.store.ReadAfterWriteTimeout; timeout != nil {
+		client.readAfterWrite = newReadAfterWrite(timeout.Duration)
+	}
+	if timeout := client.store.MaxRefTimeout; timeout != nil {
+		client.maxRefTimeout = timeout.Duration
+	}
+	onboardbase.EncryptPushedSecrets = client.store.EncryptPushedSecrets
+	if fields := client.store.SecretFields; fields != nil {
+		if fields.Key != "" {
+			onboardbase.SecretKeyField = fields.Key
+		}
+		if fields.Value != "" {
+			onboardbase.SecretValueField = fields.Value
+		}
+	}
 
 	return client, nil
 }
@@ -84,23 +268,248 @@ func (p *Provider) NewClient(ctx context.Context, store esv1beta1.GenericStore,
 func (p *Provider) ValidateStore(store esv1beta1.GenericStore) error {
 	storeSpec := store.GetSpec()
 	onboardbaseStoreSpec := storeSpec.Provider.Onboardbase
-	onboardbaseAPIKeySecretRef := onboardbaseStoreSpec.Auth.OnboardbaseAPIKey
-	if err := utils.ValidateSecretSelector(store, onboardbaseAPIKeySecretRef); err != nil {
+	if onboardbaseStoreSpec.Auth.OnboardbaseAPIKeyFile == "" {
+		onboardbaseAPIKeySecretRef := onboardbaseStoreSpec.Auth.OnboardbaseAPIKey
+		if err := utils.ValidateSecretSelector(store, onboardbaseAPIKeySecretRef); err != nil {
+			return fmt.Errorf(errInvalidStore, err)
+		}
+
+		if onboardbaseAPIKeySecretRef.Name == "" {
+			return fmt.Errorf(errInvalidStore, "onboardbaseAPIKey.name cannot be empty")
+		}
+
+		onboardbasePasscodeKeySecretRef := onboardbaseStoreSpec.Auth.OnboardbaseAPIKey
+		if err := utils.ValidateSecretSelector(store, onboardbasePasscodeKeySecretRef); err != nil {
+			return fmt.Errorf(errInvalidStore, err)
+		}
+
+		if onboardbasePasscodeKeySecretRef.Name == "" {
+			return fmt.Errorf(errInvalidStore, "onboardbasePasscode.name cannot be empty")
+		}
+	}
+
+	if !onboardbaseStoreSpec.ServerSideDecryption && onboardbaseStoreSpec.Auth.OnboardbasePasscodeFile == "" && onboardbaseStoreSpec.Auth.OnboardbasePasscode.Key == "" {
+		return fmt.Errorf(errInvalidStore, "onboardbasePasscode.key is required unless serverSideDecryption is enabled or onboardbasePasscodeFile is set")
+	}
+	if verbosity := onboardbaseStoreSpec.LogVerbosity; verbosity != nil && *verbosity < 0 {
+		return fmt.Errorf(errInvalidStore, "logVerbosity must not be negative")
+	}
+	if onboardbaseStoreSpec.EncryptPushedSecrets && onboardbaseStoreSpec.ServerSideDecryption {
+		return fmt.Errorf(errInvalidStore, "encryptPushedSecrets cannot be combined with serverSideDecryption")
+	}
+	for _, selector := range onboardbaseStoreSpec.Auth.OnboardbaseFallbackPasscodes {
+		if err := utils.ValidateSecretSelector(store, selector); err != nil {
+			return fmt.Errorf(errInvalidStore, err)
+		}
+		if selector.Name == "" || selector.Key == "" {
+			return fmt.Errorf(errInvalidStore, "onboardbaseFallbackPasscodes entries need a name and a key")
+		}
+	}
+
+	// Variables in apiHost and readApiHost resolve against the controller's
+	// environment, which may differ from the webhook's, so only the literal
+	// parts are checked.
+	apiHost := envReference.ReplaceAllString(onboardbaseStoreSpec.APIHost, "env")
+	readHost := envReference.ReplaceAllString(onboardbaseStoreSpec.ReadAPIHost, "env")
+	for _, host := range append([]string{apiHost, readHost}, onboardbaseStoreSpec.FailoverHosts...) {
+		if _, err := url.Parse(host); err != nil {
+			return fmt.Errorf(errInvalidStore, err)
+		}
+	}
+
+	if onboardbaseStoreSpec.InsecureSkipVerify && !onboardbaseStoreSpec.AllowInsecure {
+		return fmt.Errorf(errInvalidStore, errInsecureWithoutAck)
+	}
+	if _, err := dClient.CipherSuiteIDs(onboardbaseStoreSpec.TLSCipherSuites); err != nil {
 		return fmt.Errorf(errInvalidStore, err)
 	}
 
-	if onboardbaseAPIKeySecretRef.Name == "" {
-		return fmt.Errorf(errInvalidStore, "onboardbaseAPIKey.name cannot be empty")
+	if err := validateHeaders(onboardbaseStoreSpec.ExtraHeaders); err != nil {
+		return fmt.Errorf(errInvalidStore, err)
+	}
+
+	for _, patterns := range [][]string{onboardbaseStoreSpec.AllowedKeys, onboardbaseStoreSpec.DeniedKeys} {
+		if err := validateKeyPatterns(patterns); err != nil {
+			return fmt.Errorf(errInvalidStore, err)
+		}
+	}
+	if onboardbaseStoreSpec.RawPayloads && (len(onboardbaseStoreSpec.AllowedKeys) > 0 || len(onboardbaseStoreSpec.DeniedKeys) > 0 || onboardbaseStoreSpec.KeyPrefix != "") {
+		return fmt.Errorf(errInvalidStore, errRawPayloadsFiltered)
+	}
+
+	if signing := onboardbaseStoreSpec.RequestSigning; signing != nil {
+		if err := utils.ValidateSecretSelector(store, signing.KeySecretRef); err != nil {
+			return fmt.Errorf(errInvalidStore, err)
+		}
+		if signing.KeySecretRef.Name == "" || signing.KeySecretRef.Key == "" {
+			return fmt.Errorf(errInvalidStore, "requestSigning.keySecretRef name and key are required")
+		}
+		if signing.MaxBodySize < 0 {
+			return fmt.Errorf(errInvalidStore, "requestSigning.maxBodySize must not be negative")
+		}
+	}
+
+	if cache := onboardbaseStoreSpec.Cache; cache != nil {
+		if cache.SecretName == "" {
+			return fmt.Errorf(errInvalidStore, "cache.secretName cannot be empty")
+		}
+		if cache.MaxStaleness != nil && cache.MaxStaleness.Duration <= 0 {
+			return fmt.Errorf(errInvalidStore, "cache.maxStaleness must be positive")
+		}
+	}
+
+	if onboardbaseStoreSpec.ValidationProbes < 0 {
+		return fmt.Errorf(errInvalidStore, "validationProbes cannot be negative")
+	}
+
+	if retry := onboardbaseStoreSpec.Retry; retry != nil {
+		if retry.MaxRetries < 0 {
+			return fmt.Errorf(errInvalidStore, "retry.maxRetries cannot be negative")
+		}
+		for _, interval := range []*metav1.Duration{retry.BaseInterval, retry.MaxInterval} {
+			if interval != nil && interval.Duration <= 0 {
+				return fmt.Errorf(errInvalidStore, "retry intervals must be positive")
+			}
+		}
+		for _, code := range retry.RetryableStatusCodes {
+			if code < 400 || code > 599 {
+				return fmt.Errorf(errInvalidStore, fmt.Sprintf("retryable status code %d is not a 4xx or 5xx status", code))
+			}
+		}
+	}
+
+	if delay := onboardbaseStoreSpec.HedgeDelay; delay != nil && delay.Duration <= 0 {
+		return fmt.Errorf(errInvalidStore, "hedgeDelay must be positive")
+	}
+
+	if timeout := onboardbaseStoreSpec.ReadAfterWriteTimeout; timeout != nil && timeout.Duration <= 0 {
+		return fmt.Errorf(errInvalidStore, "readAfterWriteTimeout must be positive")
+	}
+
+	if timeout := onboardbaseStoreSpec.MaxRefTimeout; timeout != nil && timeout.Duration <= 0 {
+		return fmt.Errorf(errInvalidStore, "maxRefTimeout must be positive")
+	}
+
+	if onboardbaseStoreSpec.CompressionThreshold < 0 {
+		return fmt.Errorf(errInvalidStore, "compressionThreshold must not be negative")
+	}
+
+	for _, timeout := range []*metav1.Duration{onboardbaseStoreSpec.ResponseHeaderTimeout, onboardbaseStoreSpec.BodyReadTimeout} {
+		if timeout != nil && timeout.Duration <= 0 {
+			return fmt.Errorf(errInvalidStore, "responseHeaderTimeout and bodyReadTimeout must be positive")
+		}
+	}
+
+	if from := onboardbaseStoreSpec.EnvironmentFromNamespace; from != nil && (from.Label == "") == (from.Annotation == "") {
+		return fmt.Errorf(errInvalidStore, "environmentFromNamespace must set exactly one of label and annotation")
+	}
+
+	if onboardbaseStoreSpec.EnvironmentFromNamespace == nil {
+		if err := checkInheritance(onboardbaseStoreSpec.Environment, onboardbaseStoreSpec.ParentEnvironment); err != nil {
+			return fmt.Errorf(errInvalidStore, err)
+		}
+	}
+
+	if breaker := onboardbaseStoreSpec.CircuitBreaker; breaker != nil {
+		for _, circuit := range []*esv1beta1.OnboardbaseCircuit{breaker.Reads, breaker.Writes} {
+			if circuit == nil {
+				continue
+			}
+			if circuit.FailureThreshold < 1 {
+				return fmt.Errorf(errInvalidStore, "circuitBreaker failureThreshold must be at least 1")
+			}
+			if circuit.Cooldown != nil && circuit.Cooldown.Duration <= 0 {
+				return fmt.Errorf(errInvalidStore, "circuitBreaker cooldown must be positive")
+			}
+		}
+	}
+
+	for _, host := range onboardbaseStoreSpec.PayloadHosts {
+		if host == "" || strings.Contains(host, "/") {
+			return fmt.Errorf(errInvalidStore, fmt.Sprintf("payloadHosts must be host names, e.g. storage.example.com, got %q", host))
+		}
 	}
 
-	onboardbasePasscodeKeySecretRef := onboardbaseStoreSpec.Auth.OnboardbaseAPIKey
-	if err := utils.ValidateSecretSelector(store, onboardbasePasscodeKeySecretRef); err != nil {
+	if err := dClient.ValidateSuccessCodes(onboardbaseStoreSpec.SuccessCodes); err != nil {
 		return fmt.Errorf(errInvalidStore, err)
 	}
 
-	if onboardbasePasscodeKeySecretRef.Name == "" {
-		return fmt.Errorf(errInvalidStore, "onboardbasePasscode.name cannot be empty")
+	if fields := onboardbaseStoreSpec.SecretFields; fields != nil && fields.Key != "" && fields.Key == fields.Value {
+		return fmt.Errorf(errInvalidStore, "secretFields.key and secretFields.value must differ")
 	}
 
 	return nil
 }
+
+// newBackoff builds the backoff strategy configured by retry.
+func newBackoff(retry *esv1beta1.OnboardbaseRetry) dClient.Backoff {
+	base, maxInterval := dClient.DefaultBackoffBase, dClient.DefaultBackoffMax
+	if retry.BaseInterval != nil {
+		base = retry.BaseInterval.Duration
+	}
+	if retry.MaxInterval != nil {
+		maxInterval = retry.MaxInterval.Duration
+	}
+
+	switch retry.Backoff {
+	case esv1beta1.OnboardbaseBackoffConstant:
+		return dClient.ConstantBackoff{Interval: base}
+	case esv1beta1.OnboardbaseBackoffLinear:
+		return dClient.LinearBackoff{Base: base, Max: maxInterval}
+	default:
+		return dClient.ExponentialBackoff{Base: base, Max: maxInterval}
+	}
+}
+
+// newCircuitSettings builds the settings of a circuit, which is disabled when
+// circuit is nil.
+func newCircuitSettings(circuit *esv1beta1.OnboardbaseCircuit) dClient.CircuitSettings {
+	if circuit == nil {
+		return dClient.CircuitSettings{}
+	}
+	settings := dClient.CircuitSettings{Threshold: circuit.FailureThreshold, Cooldown: defaultCircuitCooldown}
+	if circuit.Cooldown != nil {
+		settings.Cooldown = circuit.Cooldown.Duration
+	}
+	return settings
+}
+
+// validateScope checks that project and environment are among the given
+// projects, listing the valid choices otherwise.
+func validateScope(projects []dClient.Project, project, environment string) error {
+	titles := make([]string, 0, len(projects))
+	for _, p := range projects {
+		if p.Title != project {
+			titles = append(titles, p.Title)
+			continue
+		}
+		for _, e := range p.Environments {
+			if e == environment {
+				return nil
+			}
+		}
+		return fmt.Errorf(errUnknownEnvironment, environment, project, strings.Join(p.Environments, ", "))
+	}
+	return fmt.Errorf(errUnknownProject, project, strings.Join(titles, ", "))
+}
+
+// envReference matches a ${VAR} reference to an environment variable.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references in s using lookup. Other text,
+// including a bare $, is kept as is.
+func expandEnv(s string, lookup func(string) (string, bool)) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		value, ok := lookup(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf(errUnresolvedEnv, s, strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
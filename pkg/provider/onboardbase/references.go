@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+// maxReferenceDepth bounds how many references are followed from a secret.
+const maxReferenceDepth = 8
+
+const (
+	referenceChainSeparator = " -> "
+
+	errReferenceCycle   = "reference cycle: %s"
+	errReferenceDepth   = "more than %d references followed: %s"
+	errResolveReference = "unable to resolve reference %s: %w"
+)
+
+// resolveReferences follows values of the form <referencePrefix><key> to the
+// value of key in the same project and environment, until a value is not a
+// reference. It is a no-op unless the store sets a reference prefix.
+func (c *Client) resolveReferences(ctx context.Context, request dClient.SecretRequest, value []byte) ([]byte, error) {
+	if c.referencePrefix == "" {
+		return value, nil
+	}
+
+	prefix := []byte(c.referencePrefix)
+	chain := []string{request.Name}
+	for bytes.HasPrefix(value, prefix) {
+		target := strings.TrimSpace(string(bytes.TrimPrefix(value, prefix)))
+		for _, name := range chain {
+			if name == target {
+				return nil, fmt.Errorf(errReferenceCycle, strings.Join(append(chain, target), referenceChainSeparator))
+			}
+		}
+		if len(chain) > maxReferenceDepth {
+			return nil, fmt.Errorf(errReferenceDepth, maxReferenceDepth, strings.Join(chain, referenceChainSeparator))
+		}
+		chain = append(chain, target)
+
+		request.Name = target
+		var err error
+		value, err = c.getSecretValue(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf(errResolveReference, strings.Join(chain, referenceChainSeparator), err)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,166 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+const (
+	// refOptionsSeparator separates a remote key from its options,
+	// e.g. `API_KEY?default=changeme`.
+	refOptionsSeparator = "?"
+	refOptionDefault    = "default"
+	// refOptionFormat selects how the value is parsed for GetSecretMap and
+	// property extraction, e.g. `CONFIG?format=yaml`.
+	refOptionFormat = "format"
+	// refOptionAssemble builds the value from several secrets sharing the
+	// remote key as prefix, e.g. `REGISTRY?assemble=dockerconfigjson`.
+	refOptionAssemble = "assemble"
+	// refOptionHeaderPrefix marks options sent as request headers,
+	// e.g. `API_KEY?header.X-Gateway-Policy=strict`.
+	refOptionHeaderPrefix = "header."
+
+	errInvalidRefOptions = "invalid options in remote key %s: %w"
+	errReservedHeader    = "header %s cannot be overridden"
+	errUnknownFormat     = "unknown format %q, expected %s or %s"
+	errInvalidYAML       = "unable to parse secret %s as YAML: %w"
+	errUnknownAssembly   = "unknown assemble value %q, expected %s"
+)
+
+const (
+	valueFormatJSON = "json"
+	valueFormatYAML = "yaml"
+)
+
+// refOptions are the per-ref settings encoded as a query string after the
+// remote key.
+type refOptions struct {
+	// defaultValue is returned when the secret does not exist.
+	defaultValue *string
+	// headers are sent with the request fetching the secret, taking
+	// precedence over the store's extraHeaders.
+	headers map[string]string
+	// format is valueFormatJSON or valueFormatYAML.
+	format string
+	// assemble is empty or assembleDockerConfigJSON.
+	assemble string
+	// transforms are applied in order to the value of the secret.
+	transforms []transform
+	// timeout is the deadline of the fetch, or zero for the caller's.
+	timeout time.Duration
+}
+
+// parseRemoteKey splits a remote key into the secret name and its options.
+func parseRemoteKey(key string) (string, refOptions, error) {
+	opts := refOptions{format: valueFormatJSON}
+	idx := strings.Index(key, refOptionsSeparator)
+	if idx < 0 {
+		return key, opts, nil
+	}
+
+	values, err := url.ParseQuery(key[idx+1:])
+	if err != nil {
+		return "", opts, fmt.Errorf(errInvalidRefOptions, key, err)
+	}
+	if values.Has(refOptionDefault) {
+		value := values.Get(refOptionDefault)
+		opts.defaultValue = &value
+	}
+	if values.Has(refOptionFormat) {
+		opts.format = strings.ToLower(values.Get(refOptionFormat))
+		if opts.format != valueFormatJSON && opts.format != valueFormatYAML {
+			return "", opts, fmt.Errorf(errInvalidRefOptions, key, fmt.Errorf(errUnknownFormat, values.Get(refOptionFormat), valueFormatJSON, valueFormatYAML))
+		}
+	}
+	if values.Has(refOptionAssemble) {
+		opts.assemble = strings.ToLower(values.Get(refOptionAssemble))
+		if opts.assemble != assembleDockerConfigJSON {
+			return "", opts, fmt.Errorf(errInvalidRefOptions, key, fmt.Errorf(errUnknownAssembly, values.Get(refOptionAssemble), assembleDockerConfigJSON))
+		}
+	}
+	if values.Has(refOptionTimeout) {
+		opts.timeout, err = parseRefTimeout(values.Get(refOptionTimeout))
+		if err != nil {
+			return "", opts, fmt.Errorf(errInvalidRefOptions, key, err)
+		}
+	}
+	if values.Has(refOptionTransform) {
+		opts.transforms, err = parseTransforms(values[refOptionTransform])
+		if err != nil {
+			return "", opts, fmt.Errorf(errInvalidRefOptions, key, err)
+		}
+	}
+	for name := range values {
+		if !strings.HasPrefix(name, refOptionHeaderPrefix) {
+			continue
+		}
+		if opts.headers == nil {
+			opts.headers = map[string]string{}
+		}
+		opts.headers[strings.TrimPrefix(name, refOptionHeaderPrefix)] = values.Get(name)
+	}
+	if err := validateHeaders(opts.headers); err != nil {
+		return "", opts, fmt.Errorf(errInvalidRefOptions, key, err)
+	}
+	return key[:idx], opts, nil
+}
+
+// coordinatesSeparator separates the project, environment and secret name
+// of a remote key that overrides the store's project and environment, e.g.
+// `billing:staging:DB_PASSWORD`.
+const coordinatesSeparator = ":"
+
+// parseCoordinates splits a secret name into its project, environment and
+// name. Project and environment are empty when name is not made of exactly
+// three non-empty parts, in which case it is taken literally: secret names
+// may contain the separator themselves.
+func parseCoordinates(name string) (string, string, string) {
+	parts := strings.Split(name, coordinatesSeparator)
+	if len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "" {
+		return parts[0], parts[1], parts[2]
+	}
+	return "", "", name
+}
+
+// validateHeaders rejects headers that would replace the API credentials.
+func validateHeaders(headers map[string]string) error {
+	for name := range headers {
+		if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(dClient.HeaderAPIKey) {
+			return fmt.Errorf(errReservedHeader, name)
+		}
+	}
+	return nil
+}
+
+// toJSON returns value as JSON, converting it from YAML when opts say so.
+func (opts refOptions) toJSON(key string, value []byte) ([]byte, error) {
+	if opts.format != valueFormatYAML {
+		return value, nil
+	}
+	converted, err := yaml.YAMLToJSON(value)
+	if err != nil {
+		return nil, fmt.Errorf(errInvalidYAML, key, err)
+	}
+	return converted, nil
+}
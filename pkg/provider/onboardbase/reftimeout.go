@@ -0,0 +1,78 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
+)
+
+const (
+	// refOptionTimeout sets the deadline of a GetSecret, e.g.
+	// `REPORT?timeout=90s`. The timeoutTag of dataFrom.find does the same
+	// for GetAllSecrets.
+	refOptionTimeout = "timeout"
+	timeoutTag       = "timeout"
+
+	// defaultMaxRefTimeout bounds ref timeouts when the store does not set
+	// maxRefTimeout.
+	defaultMaxRefTimeout = 2 * time.Minute
+
+	errInvalidTimeout    = "invalid timeout %q: %s"
+	errTimeoutExceedsMax = "timeout %s exceeds the store's maxRefTimeout of %s"
+)
+
+// parseRefTimeout parses a timeout set by ref metadata, which must be a
+// positive duration.
+func parseRefTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf(errInvalidTimeout, value, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf(errInvalidTimeout, value, "must be positive")
+	}
+	return timeout, nil
+}
+
+// findTimeout returns the timeout set by the timeoutTag of ref, or zero.
+func findTimeout(ref esv1beta1.ExternalSecretFind) (time.Duration, error) {
+	value, ok := ref.Tags[timeoutTag]
+	if !ok {
+		return 0, nil
+	}
+	return parseRefTimeout(value)
+}
+
+// withRefTimeout derives the context of an operation with the timeout set
+// by its ref, which cannot exceed the store's maxRefTimeout. Without a
+// timeout, ctx is returned as is.
+func (c *Client) withRefTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	if timeout == 0 {
+		return ctx, func() {}, nil
+	}
+	maxTimeout := c.maxRefTimeout
+	if maxTimeout == 0 {
+		maxTimeout = defaultMaxRefTimeout
+	}
+	if timeout > maxTimeout {
+		return nil, nil, fmt.Errorf(errTimeoutExceedsMax, timeout, maxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
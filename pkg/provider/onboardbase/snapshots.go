@@ -0,0 +1,130 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+type snapshotKey struct {
+	project     string
+	environment string
+}
+
+func (k snapshotKey) String() string {
+	return k.project + "/" + k.environment
+}
+
+// secretSnapshots holds the secrets of each environment read through a
+// Client, so that the keys of an ExternalSecret, fetched one GetSecret at a
+// time, cost a single fetch per environment. A Client serves one reconcile,
+// which bounds how stale a snapshot gets. Concurrent calls for an environment
+// without a snapshot share one fetch, and its decryption.
+type secretSnapshots struct {
+	mu      sync.Mutex
+	secrets map[snapshotKey]map[string]*dClient.SecretResponse
+	// generation counts invalidations, so that a fetch started before one
+	// does not store its result.
+	generation uint64
+	fetches    singleflight.Group
+}
+
+func newSecretSnapshots() *secretSnapshots {
+	return &secretSnapshots{secrets: map[snapshotKey]map[string]*dClient.SecretResponse{}}
+}
+
+// get returns a secret from the snapshot of its environment, fetching the
+// snapshot first if needed. Failed fetches are not remembered, and their
+// error is returned to every caller waiting on them. A caller whose ctx is
+// done stops waiting, while the fetch goes on for the others.
+func (s *secretSnapshots) get(ctx context.Context, onboardbase SecretsClientInterface, request dClient.SecretRequest) (*dClient.SecretResponse, error) {
+	key := snapshotKey{project: request.Project, environment: request.Environment}
+	s.mu.Lock()
+	secrets, ok := s.secrets[key]
+	generation := s.generation
+	s.mu.Unlock()
+
+	if !ok {
+		result := s.fetches.DoChan(key.String(), func() (interface{}, error) {
+			secrets, err := onboardbase.GetSecretsByNames(detachedContext{ctx}, dClient.SecretsRequest{Project: request.Project, Environment: request.Environment}, nil)
+			if err != nil {
+				return nil, err
+			}
+			s.mu.Lock()
+			if s.generation == generation {
+				s.secrets[key] = secrets
+			}
+			s.mu.Unlock()
+			return secrets, nil
+		})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case r := <-result:
+			if r.Err != nil {
+				return nil, r.Err
+			}
+			secrets = r.Val.(map[string]*dClient.SecretResponse)
+		}
+	}
+
+	secret, ok := secrets[request.Name]
+	if !ok {
+		return nil, &dClient.APIError{Err: dClient.ErrSecretNotFound, Message: fmt.Sprintf("secret %s for project '%s' and environment '%s' not found", request.Name, request.Project, request.Environment)}
+	}
+	return secret, nil
+}
+
+// detachedContext keeps the values of a context but not its cancellation, so
+// that a fetch shared by several callers is not cut short by the one that
+// started it.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// invalidate drops the snapshot of an environment after writing to it.
+func (s *secretSnapshots) invalidate(project, environment string) {
+	if s == nil {
+		return
+	}
+	key := snapshotKey{project: project, environment: environment}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+	delete(s.secrets, key)
+	s.fetches.Forget(key.String())
+}
+
+// fetchSecret returns a single secret, from the environment's snapshot when
+// the Client keeps them. Requests with their own headers are sent as is.
+// request names the secret as the store exposes it.
+func (c *Client) fetchSecret(ctx context.Context, request dClient.SecretRequest) (*dClient.SecretResponse, error) {
+	request.Name = c.keys.remoteKey(request.Name)
+	if c.snapshots == nil || len(request.Headers) != 0 {
+		return c.onboardbase.GetSecret(ctx, request)
+	}
+	return c.snapshots.get(ctx, c.onboardbase, request)
+}
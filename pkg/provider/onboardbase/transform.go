@@ -0,0 +1,106 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	// refOptionTransform lists transforms applied in order to the value of a
+	// secret, e.g. `CONFIG?transform=trim,base64decode,property:db.password`.
+	// The option may also be repeated.
+	refOptionTransform = "transform"
+	// transformArgSeparator separates a transform from its argument.
+	transformArgSeparator = ":"
+
+	transformTrim         = "trim"
+	transformBase64Decode = "base64decode"
+	transformProperty     = "property"
+
+	errUnknownTransform     = "unknown transform %q, expected %s, %s or %s"
+	errTransformArgument    = "transform %s %s"
+	errTransformStepFailed  = "transform %d (%s) of secret %s failed: %w"
+	errTransformNotAllowed  = "takes no argument"
+	errTransformArgRequired = "requires an argument, e.g. property:db.password"
+)
+
+// transform is a named operation applied to the value of a secret.
+type transform struct {
+	name string
+	arg  string
+}
+
+func (t transform) String() string {
+	if t.arg == "" {
+		return t.name
+	}
+	return t.name + transformArgSeparator + t.arg
+}
+
+// parseTransforms parses the values of the transform option, each a comma
+// separated list of transforms.
+func parseTransforms(values []string) ([]transform, error) {
+	var transforms []transform
+	for _, value := range values {
+		for _, step := range strings.Split(value, ",") {
+			name, arg := step, ""
+			if idx := strings.Index(step, transformArgSeparator); idx >= 0 {
+				name, arg = step[:idx], step[idx+1:]
+			}
+			name = strings.ToLower(strings.TrimSpace(name))
+			switch name {
+			case transformTrim, transformBase64Decode:
+				if arg != "" {
+					return nil, fmt.Errorf(errTransformArgument, name, errTransformNotAllowed)
+				}
+			case transformProperty:
+				if arg == "" {
+					return nil, fmt.Errorf(errTransformArgument, name, errTransformArgRequired)
+				}
+			default:
+				return nil, fmt.Errorf(errUnknownTransform, name, transformTrim, transformBase64Decode, transformProperty)
+			}
+			transforms = append(transforms, transform{name: name, arg: arg})
+		}
+	}
+	return transforms, nil
+}
+
+// applyTransforms runs the transforms of opts on the value of secret key, in
+// order. The error names the step that failed.
+func (opts refOptions) applyTransforms(key string, value []byte) ([]byte, error) {
+	for i, t := range opts.transforms {
+		var err error
+		switch t.name {
+		case transformTrim:
+			value = bytes.TrimSpace(value)
+		case transformBase64Decode:
+			value, err = base64.StdEncoding.DecodeString(string(value))
+		case transformProperty:
+			value, err = opts.toJSON(key, value)
+			if err == nil {
+				value, err = getProperty(value, key, t.arg)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf(errTransformStepFailed, i+1, t, key, err)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,109 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package onboardbase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	esv1beta1 "github.com/external-secrets/external-secrets/apis/externalsecrets/v1beta1"
+	dClient "github.com/external-secrets/external-secrets/pkg/provider/onboardbase/client"
+)
+
+const (
+	errWholeSecretRemoteKey = "remoteKey %q must be empty when pushing a whole secret; set pushKeyPrefix to prefix its keys"
+	errPushKeys             = "could not push keys: %s"
+)
+
+// PushAllSecrets pushes every key of data, prefixed with the store's
+// pushKeyPrefix, as a secret of its own in a single update. Keys are checked
+// and merged as PushSecret does; when any of them fails, nothing is pushed
+// and the error lists each failing key. It returns the prefixed name each key
+// was pushed as.
+func (c *Client) PushAllSecrets(ctx context.Context, data map[string][]byte, remoteRef esv1beta1.PushRemoteRef) (map[string]string, error) {
+	if remoteRef.GetRemoteKey() != "" {
+		return nil, fmt.Errorf(errWholeSecretRemoteKey, remoteRef.GetRemoteKey())
+	}
+	if len(data) == 0 {
+		return map[string]string{}, nil
+	}
+
+	names := make([]string, 0, len(data))
+	keys := make(map[string]string, len(data))
+	for key := range data {
+		name := c.pushKeyPrefix + key
+		names = append(names, name)
+		keys[name] = key
+	}
+	sort.Strings(names)
+
+	var remote map[string]*dClient.SecretResponse
+	if c.pushMergeStrategy == esv1beta1.OnboardbasePushMergeLocalWins || c.pushMergeStrategy == esv1beta1.OnboardbasePushMergeRemoteWins {
+		remoteNames := make([]string, 0, len(names))
+		for _, name := range names {
+			remoteNames = append(remoteNames, c.keys.remoteKey(name))
+		}
+		var err error
+		remote, err = c.onboardbase.GetSecretsByNames(ctx, dClient.SecretsRequest{Project: c.project, Environment: c.environment}, remoteNames)
+		if err != nil {
+			return nil, fmt.Errorf(errPushKeys, fmt.Sprintf("%s: %v", strings.Join(names, ", "), err))
+		}
+	}
+
+	request := dClient.UpdateSecretsRequest{Project: c.project, Environment: c.environment}
+	var failures []string
+	for _, name := range names {
+		value := data[keys[name]]
+		if existing, ok := remote[c.keys.remoteKey(name)]; ok {
+			merged, err := mergeJSON(name, []byte(existing.Value), value, c.pushMergeStrategy)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+				continue
+			}
+			value = merged
+		}
+		if err := c.checkValueSize(value); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		request.Secrets = append(request.Secrets, dClient.RawSecret{Key: c.keys.remoteKey(name), Value: string(value)})
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf(errPushKeys, strings.Join(failures, "; "))
+	}
+
+	err := c.onboardbase.UpdateSecrets(ctx, request)
+	c.snapshots.invalidate(c.project, c.environment)
+	if err != nil {
+		return nil, fmt.Errorf(errPushKeys, fmt.Sprintf("%s: %v", strings.Join(names, ", "), err))
+	}
+
+	if c.readAfterWrite != nil {
+		for _, secret := range request.Secrets {
+			readRequest := dClient.SecretRequest{Project: c.project, Environment: c.environment, Name: secret.Key}
+			if err := c.readAfterWrite.waitVisible(ctx, c.onboardbase, readRequest, secret.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	pushed := make(map[string]string, len(keys))
+	for name, key := range keys {
+		pushed[key] = name
+	}
+	return pushed, nil
+}
@@ -87,6 +87,23 @@ func (v *Client) PushSecret(ctx context.Context, value []byte, remoteRef esv1bet
 	return v.SetSecretFn()
 }
 
+// PushAllSecrets pushes each key of data under its own name, as the
+// PushSecret controller does for data that names no secretKey.
+func (v *Client) PushAllSecrets(ctx context.Context, data map[string][]byte, remoteRef esv1beta1.PushRemoteRef) (map[string]string, error) {
+	pushed := make(map[string]string, len(data))
+	for key, value := range data {
+		v.SetSecretArgs[key] = SetSecretCallArgs{
+			Value:     value,
+			RemoteRef: remoteRef,
+		}
+		pushed[key] = key
+	}
+	if err := v.SetSecretFn(); err != nil {
+		return nil, err
+	}
+	return pushed, nil
+}
+
 func (v *Client) DeleteSecret(ctx context.Context, remoteRef esv1beta1.PushRemoteRef) error {
 	return v.DeleteSecretFn()
 }